@@ -26,21 +26,50 @@ import (
 
 // hold the statistics for a phase of a program run.
 type stats struct {
-	name       string // the name of this statistics type
-	leftCount  int64  // left side total file count
-	leftSize   int64  // left side total file size in bytes
-	rightCount int64
-	rightSize  int64
+	name   string  // the name of this statistics type
+	counts []int64 // total file count on each side
+	sizes  []int64 // total file size in bytes on each side
 }
 
 // update this stats object on the given side with one file's size
-func (self *stats) update(side bool, size int64) {
-	if side {
-		self.rightSize += size
-		self.rightCount++
-	} else {
-		self.leftSize += size
-		self.leftCount++
+func (self *stats) update(side int, size int64) {
+	for len(self.counts) <= side {
+		self.counts = append(self.counts, 0)
+		self.sizes = append(self.sizes, 0)
+	}
+	self.counts[side]++
+	self.sizes[side] += size
+}
+
+// return the file count and total size on all sides
+func (self *stats) total() (count, size int64) {
+	for side := range self.counts {
+		count += self.counts[side]
+		size += self.sizes[side]
+	}
+	return
+}
+
+// return the file count and total size on the given side
+func (self *stats) get(side int) (int64, int64) {
+	if side >= len(self.counts) {
+		return 0, 0
+	}
+	return self.counts[side], self.sizes[side]
+}
+
+// Update the output stats, and any histogram, with an entry that was output.
+// Directory sizes are assumed zero for the stats, and directories aren't
+// counted in histograms.
+func (self *Context) updateOutputStats(entry fileEntry) {
+	if strings.HasSuffix(entryPath(entry), "/") {
+		self.outputStats.update(sideIndex(entry), 0)
+		return
+	}
+	size := entry.getNumericFieldOrZero(ColSize)
+	self.outputStats.update(sideIndex(entry), size)
+	if self.Histogram != nil {
+		self.updateHistogram(entry, size)
 	}
 }
 
@@ -56,11 +85,23 @@ func (self *Context) calcNeededCols() {
 	for _, col := range self.KeyCols.cols {
 		self.neededCols[col] = true // all compare keys
 	}
+	for _, col := range self.IdentityCols.cols {
+		self.neededCols[col] = true // all identity keys
+	}
+	for _, col := range self.SideCols.cols {
+		self.neededCols[col] = true // all side-by-side output columns
+	}
+	for _, col := range self.GroupCols.cols {
+		self.neededCols[col] = true // all group columns
+	}
 	for _, filt := range self.PreFilterArgs {
-		self.neededCols[filt.column] = true // all prefilter fields
+		filt.addColumns(self.neededCols) // all prefilter fields
 	}
 	for _, filt := range self.PostFilterArgs {
-		self.neededCols[filt.column] = true // all postfilter fields
+		filt.addColumns(self.neededCols) // all postfilter fields
+	}
+	for _, filt := range self.PruneFilterArgs {
+		filt.addColumns(self.neededCols) // all prunefilter fields
 	}
 }
 
@@ -69,12 +110,23 @@ func (self *Context) needsCol(col Column) bool {
 	return self.neededCols[col]
 }
 
-// Compare file entries on the right side and left side using the compare key columns,
-// and determine which ones match. Update the entries with the relevant info, and update
-// the context statistics objects.
+// Compare file entries on all sides using the compare key columns, and
+// determine which ones match. Modification times may match within a
+// tolerance. Update the entries with the relevant info, and update the
+// context statistics objects.
 func (self *Context) analyzeMatches() {
 	entries := self.entries
 	unmatchedLeft := false // any files on the left side were unmatched by a file on right
+	nSides := self.numSides()
+	for _, entry := range entries {
+		if side := sideIndex(entry); side >= nSides {
+			nSides = side + 1
+		}
+	}
+	if nSides < 2 {
+		nSides = 2
+	}
+	redun := make([]int, nSides) // count of entries on each side of a group
 
 	if len(self.SortCols.cols) == 0 {
 		// if not sorting later, use a copied list and leave original scan order in context
@@ -83,44 +135,58 @@ func (self *Context) analyzeMatches() {
 	}
 	// sort the entries using the values in the compare key columns
 	self.outTempf(0, "Analyzing... %d files", len(entries))
-	sorter := newEntrySorter(self, entries, self.KeyCols.cols)
+	sorter := newEntrySorter(self, entries, self.matchSortCols())
+	sorter.keys = self.mapKeyEntries(entries)
 	sort.Sort(sorter)
+	groups := self.findMatchGroups(entries, sorter.keys)
 
 	base := 0 // first entry in list still matching current file
 	needRedun := self.needsCol(ColRedundancy)
 	needRedunIdx := self.needsCol(ColRedunIdx)
+	needEntropyJump := self.needsCol(ColEntJump)
 	// scan list looking for matching groups of files
 	for cur := 1; cur < len(entries)+1; cur++ {
 		differs := true
 		if cur < len(entries) {
-			// compare this entry to the base entry of this match group
-			d, notNull := entries[base].compare(entries[cur], self.KeyCols.cols)
-			self.checkNullCompare(notNull)
-			differs = d != 0
+			// compare this entry's group to the base entry's group
+			differs = groups[cur] != groups[base]
 		}
 		if differs {
 			// file differs (or end of list); need to end the old group and start a new group
-			leftRedun, rightRedun := 0, 0
+			for side := range redun {
+				redun[side] = 0
+			}
 			// compute redundancy counts for each side of this group
 			for i := base; i < cur; i++ {
-				pRedun := &leftRedun
-				if entries[i].getBoolFieldOrFalse(ColSide) {
-					pRedun = &rightRedun
-				}
-				*pRedun++
+				side := sideIndex(entries[i])
+				redun[side]++
 				// Update redundancy index col if needed
 				if needRedunIdx {
-					entries[i].setNumericField(ColRedunIdx, int64(*pRedun))
+					entries[i].setNumericField(ColRedunIdx, int64(redun[side]))
 				}
 			}
+			if needEntropyJump {
+				setEntropyJumps(entries[base:cur])
+			}
 			// if there was at least one file on each side, file is considered to "match"
-			matched := leftRedun > 0 && rightRedun > 0
+			matched := true
+			for _, n := range redun {
+				matched = matched && n > 0
+			}
+			// with more than two sides, membership shows the sides holding the file
+			var membership string
+			if nSides > 2 {
+				membership = formatSides(redun)
+			}
 			// update all of the files in the match group
 			for ; base < cur; base++ {
 				// set the matched field in the entry
 				entries[base].setBoolField(ColMatched, matched)
-				es := entries[base].getBoolFieldOrFalse(ColSide)
-				if !es && !matched {
+				if membership != "" {
+					entries[base].setStringField(ColMembership, membership)
+				}
+				es := sideIndex(entries[base])
+				if es == 0 && !matched {
 					unmatchedLeft = true // any unmatched left side files trigger an error with --verify
 				}
 				// update the matched and unmatched scan stats
@@ -136,15 +202,19 @@ func (self *Context) analyzeMatches() {
 				}
 				// update redundancy field if needed
 				if needRedun {
-					redun := leftRedun
-					if es {
-						redun = rightRedun
-					}
-					entries[base].setNumericField(ColRedundancy, int64(redun))
+					entries[base].setNumericField(ColRedundancy, int64(redun[es]))
 				}
 			}
 		}
 	}
+	if self.DetectMoves {
+		self.detectMoves(entries)
+	}
+	if self.Merge {
+		self.classifyMerge(entries, sorter.keys)
+	} else if self.usesIdentity() {
+		self.classifyChanges(entries, sorter.keys)
+	}
 	if unmatchedLeft && self.Verify {
 		self.onError("At least one entry on the left was unmatched (--verify was specified)")
 	}
@@ -157,26 +227,56 @@ func (self *Context) checkNullCompare(notNull bool) {
 	}
 }
 
+// Return the sides that have roots.
+func (self *Context) sidesWithRoots() []int {
+	var sides []int
+	for side := 0; side < self.numSides(); side++ {
+		if len(self.Roots[side]) > 0 {
+			sides = append(sides, side)
+		}
+	}
+	return sides
+}
+
 // Using the information in all of the stats objects, format the summary
 // information into a 2D array of strings. The exact fields selected
-// depend on the scan options, such as if there are roots on both sides.
+// depend on the scan options, such as if there are roots on several sides.
 func (self *Context) calcSummaryInfo() [][]string {
-	hasLeft := len(self.Roots[false]) > 0
-	hasRight := len(self.Roots[true]) > 0
+	sides := self.sidesWithRoots()
 
 	var allStats []*stats
 
-	// more stats are relevant if we have scans on both sides
-	if hasLeft && hasRight {
+	// more stats are relevant if we have scans on several sides
+	if len(sides) > 1 {
 		allStats = []*stats{
-			&self.scanStats, &self.indexStats, &self.unmatchedStats, &self.matchingStats, &self.outputStats,
+			&self.scanStats, &self.indexStats, &self.unmatchedStats, &self.matchingStats,
+		}
+		if self.Merge {
+			// break down entries by kind of merge
+			allStats = append(allStats, &self.unchangedStats, &self.changedBStats, &self.changedCStats,
+				&self.bothStats, &self.conflictStats)
+		} else if self.usesIdentity() {
+			// break down entries by kind of change
+			allStats = append(allStats, &self.addedStats, &self.removedStats, &self.modifiedStats, &self.unchangedStats)
+			if self.DetectMoves {
+				allStats = append(allStats, &self.movedStats)
+			}
 		}
+		allStats = append(allStats, &self.outputStats)
 	} else {
 		allStats = []*stats{
 			&self.scanStats, &self.indexStats, &self.outputStats,
 		}
 	}
 
+	// duplicate groups, and what they could reclaim, follow the output
+	if self.Duplicates {
+		allStats = append(allStats, &self.duplicateStats, &self.reclaimStats)
+		if self.Dedupe != "" {
+			allStats = append(allStats, &self.dedupeStats)
+		}
+	}
+
 	// initialize header and stat names
 	header := []string{"STATISTICS:"}
 
@@ -185,24 +285,18 @@ func (self *Context) calcSummaryInfo() [][]string {
 		out = append(out, []string{stat.name})
 	}
 
-	if hasLeft {
-		// output stat columns for left side stats
-		if hasRight {
-			header = append(header, "L:Count", "L:Size")
+	for _, side := range sides {
+		// output stat columns for each side, named if there is more than the left side
+		if len(sides) > 1 || side > 0 {
+			name := self.sideName(side)
+			header = append(header, name+":Count", name+":Size")
 		} else {
 			header = append(header, "Count", "Size")
 		}
 		for i, stat := range allStats {
-			out[i] = append(out[i], self.formatNumber(stat.leftCount))
-			out[i] = append(out[i], self.formatNumber(stat.leftSize))
-		}
-	}
-	if hasRight {
-		// output stat columns for right side stats
-		header = append(header, "R:Count", "R:Size")
-		for i, stat := range allStats {
-			out[i] = append(out[i], self.formatNumber(stat.rightCount))
-			out[i] = append(out[i], self.formatNumber(stat.rightSize))
+			count, size := stat.get(side)
+			out[i] = append(out[i], self.formatNumber(count))
+			out[i] = append(out[i], self.formatNumber(size))
 		}
 	}
 	out = append([][]string{header}, out...) // insert header at top of output
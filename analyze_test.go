@@ -26,12 +26,13 @@ import (
 func Test_stats_update(t *testing.T) {
 	var tests = []struct {
 		size int64
-		side bool
+		side int
 		want stats
 	}{
-		{3, false, stats{"", 1, 3, 0, 0}},
-		{5, true, stats{"", 1, 3, 1, 5}},
-		{4, false, stats{"", 2, 7, 1, 5}},
+		{3, 0, stats{"", []int64{1}, []int64{3}}},
+		{5, 1, stats{"", []int64{1, 1}, []int64{3, 5}}},
+		{4, 0, stats{"", []int64{2, 1}, []int64{7, 5}}},
+		{6, 3, stats{"", []int64{2, 1, 0, 1}, []int64{7, 5, 0, 6}}},
 	}
 	st := stats{}
 	for _, test := range tests {
@@ -113,12 +114,12 @@ func Test_Context_checkNullCompare(t *testing.T) {
 func Test_Context_calcSummaryInfo(t *testing.T) {
 	ctx := NewContext()
 	ctx.GroupNumerics = true
-	ctx.Roots[false] = []string{"r1"}
-	ctx.scanStats = stats{"scan", 1, 2, 3, 4}
-	ctx.indexStats = stats{"index", 10, 20, 30, 40}
-	ctx.unmatchedStats = stats{"unmatched", 100, 200, 300, 400}
-	ctx.matchingStats = stats{"matching", 1000, 2000, 3000, 4000}
-	ctx.outputStats = stats{"output", 10000, 200000000, 30000, 40000}
+	ctx.Roots[0] = []string{"r1"}
+	ctx.scanStats = stats{"scan", []int64{1, 3}, []int64{2, 4}}
+	ctx.indexStats = stats{"index", []int64{10, 30}, []int64{20, 40}}
+	ctx.unmatchedStats = stats{"unmatched", []int64{100, 300}, []int64{200, 400}}
+	ctx.matchingStats = stats{"matching", []int64{1000, 3000}, []int64{2000, 4000}}
+	ctx.outputStats = stats{"output", []int64{10000, 30000}, []int64{200000000, 40000}}
 	want := [][]string{
 		{"STATISTICS:", " Count", "       Size"},
 		{"       scan", "     1", "          2"},
@@ -128,7 +129,7 @@ func Test_Context_calcSummaryInfo(t *testing.T) {
 	got := ctx.calcSummaryInfo()
 	checkVal(t, want, got)
 
-	ctx.Roots[true] = []string{"r2"}
+	ctx.Roots[1] = []string{"r2"}
 	want = [][]string{
 		{"STATISTICS:", "L:Count", "     L:Size", "R:Count", "R:Size"},
 		{"       scan", "      1", "          2", "      3", "     4"},
@@ -139,4 +140,17 @@ func Test_Context_calcSummaryInfo(t *testing.T) {
 	}
 	got = ctx.calcSummaryInfo()
 	checkVal(t, want, got)
+
+	ctx.Roots[2] = []string{"r3"}
+	ctx.scanStats.update(2, 5)
+	want = [][]string{
+		{"STATISTICS:", "A:Count", "     A:Size", "B:Count", "B:Size", "C:Count", "C:Size"},
+		{"       scan", "      1", "          2", "      3", "     4", "      1", "     5"},
+		{"      index", "     10", "         20", "     30", "    40", "      0", "     0"},
+		{"  unmatched", "    100", "        200", "    300", "   400", "      0", "     0"},
+		{"   matching", "  1,000", "      2,000", "  3,000", " 4,000", "      0", "     0"},
+		{"     output", " 10,000", "200,000,000", " 30,000", "40,000", "      0", "     0"},
+	}
+	got = ctx.calcSummaryInfo()
+	checkVal(t, want, got)
 }
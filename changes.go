@@ -0,0 +1,146 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"sort"
+	"strings"
+)
+
+// Values of the change column
+const (
+	changeAdded     = "added"
+	changeRemoved   = "removed"
+	changeModified  = "modified"
+	changeUnchanged = "unchanged"
+	changeMoved     = "moved"
+)
+
+// Return true if the entries are paired by identity key in this run.
+func (self *Context) usesIdentity() bool {
+	return len(self.IdentityCols.cols) > 0
+}
+
+// Return the names of the compare key columns whose values differ between
+// two entries, comma-separated. The mtime tolerance is used for time columns.
+func (self *Context) changedColumns(e1, e2 fileEntry) string {
+	var names []string
+	tolerant := self.usesMtimeTolerance()
+	for _, col := range self.KeyCols.cols {
+		col &^= ColInvertFlag
+		if tolerant && (col == ColMtime || col == ColMstamp) {
+			t1, ok1 := e1.getNumericField(ColMstamp)
+			t2, ok2 := e2.getNumericField(ColMstamp)
+			self.checkNullCompare(ok1 && ok2)
+			if ok1 != ok2 || ok1 && !self.mtimesMatch(t1, t2) {
+				names = append(names, col.String())
+			}
+			continue
+		}
+		d, notNull := e1.compare(e2, []Column{col})
+		self.checkNullCompare(notNull)
+		if d != 0 {
+			names = append(names, col.String())
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Pair the entries on each side by the identity key columns, and classify
+// each one as added, removed, modified or unchanged (or moved, if move
+// detection paired it with an entry of another identity), setting the
+// change and changed columns and updating the statistics for each kind of
+// change. If keys is not nil, it holds the entries to compare in place of
+// each entry. Entries with the same identity on the other side are compared
+// with the first one of them.
+func (self *Context) classifyChanges(entries, keys []fileEntry) {
+	cmp := entries
+	if keys != nil {
+		cmp = keys
+	}
+	// sort indexes by identity, leaving the entries in place
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		d, notNull := cmp[order[a]].compare(cmp[order[b]], self.IdentityCols.cols)
+		self.checkNullCompare(notNull)
+		return d < 0
+	})
+
+	start := 0
+	for end := 1; end <= len(order); end++ {
+		if end < len(order) {
+			d, _ := cmp[order[start]].compare(cmp[order[end]], self.IdentityCols.cols)
+			if d == 0 {
+				continue
+			}
+		}
+		// find the first entry on each side with this identity
+		first := [2]int{-1, -1}
+		for _, i := range order[start:end] {
+			if s := sideIndex(entries[i]); first[s] < 0 {
+				first[s] = i
+			}
+		}
+		for _, i := range order[start:end] {
+			side := sideIndex(entries[i])
+			other := first[1-side]
+			change, changed := "", ""
+			switch {
+			case other < 0 && entries[i].isMoved():
+				change = changeMoved
+			case other < 0 && side == 0:
+				change = changeRemoved
+			case other < 0:
+				change = changeAdded
+			default:
+				changed = self.changedColumns(cmp[i], cmp[other])
+				change = changeUnchanged
+				if changed != "" {
+					change = changeModified
+				}
+			}
+			entries[i].setStringField(ColChange, change)
+			entries[i].setStringField(ColChanged, changed)
+			size := entries[i].getNumericFieldOrZero(ColSize)
+			if strings.HasSuffix(entryPath(entries[i]), "/") {
+				size = 0
+			}
+			self.changeStats(change).update(side, size)
+		}
+		start = end
+	}
+}
+
+// Return the statistics object for a kind of change.
+func (self *Context) changeStats(change string) *stats {
+	switch change {
+	case changeAdded:
+		return &self.addedStats
+	case changeRemoved:
+		return &self.removedStats
+	case changeModified:
+		return &self.modifiedStats
+	case changeUnchanged:
+		return &self.unchangedStats
+	}
+	return &self.movedStats
+}
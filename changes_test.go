@@ -0,0 +1,71 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func Test_Context_changedColumns(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize, ColMtime | ColInvertFlag, ColMd5}}
+	e1 := fileEntry{ColPath: "a", ColSize: int64(1), ColMstamp: int64(1000), ColMd5: "11"}
+	e2 := fileEntry{ColPath: "a", ColSize: int64(2), ColMstamp: int64(1001), ColMd5: "11"}
+	checkVal(t, "size,mtime", ctx.changedColumns(e1, e2))
+	checkVal(t, "", ctx.changedColumns(e1, e1))
+	ctx.MtimeTolerance = 2
+	checkVal(t, "size", ctx.changedColumns(e1, e2))
+	checkVal(t, 0, ctx.nullErrorCount)
+	checkVal(t, "md5", ctx.changedColumns(e1, fileEntry{ColPath: "a", ColSize: int64(1), ColMstamp: int64(1000)}))
+	checkVal(t, 1, ctx.nullErrorCount)
+}
+
+func Test_Context_classifyChanges(t *testing.T) {
+	ctx := NewContext()
+	ctx.outputState.errStream = ioutil.Discard
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize, ColMd5}}
+	ctx.IdentityCols = ColSelector{cols: []Column{ColPath}}
+	ctx.DetectMoves = true
+	ctx.moveDigest = ColMd5
+	ctx.entries = []fileEntry{
+		{ColPath: "same", ColSize: int64(1), ColMd5: "11", ColSide: int64(0)},
+		{ColPath: "same", ColSize: int64(1), ColMd5: "11", ColSide: int64(1)},
+		{ColPath: "mod", ColSize: int64(2), ColMd5: "22", ColSide: int64(0)},
+		{ColPath: "mod", ColSize: int64(2), ColMd5: "23", ColSide: int64(1)},
+		{ColPath: "gone", ColSize: int64(3), ColMd5: "33", ColSide: int64(0)},
+		{ColPath: "new", ColSize: int64(4), ColMd5: "44", ColSide: int64(1)},
+		{ColPath: "old/m", ColSize: int64(5), ColMd5: "55", ColSide: int64(0)},
+		{ColPath: "new/m", ColSize: int64(5), ColMd5: "55", ColSide: int64(1)},
+		{ColPath: "d/", ColSize: int64(9), ColMd5: "", ColSide: int64(1)},
+	}
+	ctx.analyzeMatches()
+	var changes, changed []string
+	for _, entry := range ctx.entries {
+		changes = append(changes, entry[ColChange].(string))
+		changed = append(changed, entry[ColChanged].(string))
+	}
+	checkVal(t, []string{"unchanged", "unchanged", "modified", "modified", "removed", "added", "moved", "moved", "added"}, changes)
+	checkVal(t, []string{"", "", "md5", "md5", "", "", "", "", ""}, changed)
+	checkVal(t, stats{"Added:", []int64{0, 2}, []int64{0, 4}}, ctx.addedStats)
+	checkVal(t, stats{"Removed:", []int64{1}, []int64{3}}, ctx.removedStats)
+	checkVal(t, stats{"Modified:", []int64{1, 1}, []int64{2, 2}}, ctx.modifiedStats)
+	checkVal(t, stats{"Unchanged:", []int64{1, 1}, []int64{1, 1}}, ctx.unchangedStats)
+	checkVal(t, stats{"Moved:", []int64{1, 1}, []int64{5, 5}}, ctx.movedStats)
+}
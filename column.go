@@ -36,8 +36,8 @@ const (
 	ColMtime             // file mod time, RFC3339 format in UTC
 	ColMstamp            // mtime, unix timestamp
 	ColDevice            // ID of device file resides in
-	ColSide              // "side" of the scan, false=left true=right
-	ColMatched           // true if this file matches any on the other side
+	ColSide              // "side" of the scan, 0=left 1=right, then further sides
+	ColMatched           // true if this file matches one on every other side
 	ColMembership        // matching flags in string format
 	ColRedundancy        // number of matches of this file on this side
 	ColRedunIdx          // the index of this entry within equivalent entries on this side
@@ -53,6 +53,27 @@ const (
 	ColSha256            // sha256 digest
 	ColSha512            // sha512 digest
 	ColMd5               // md5 digest
+	ColElfArch           // ELF machine architecture
+	ColElfType           // ELF object type (exec, shared, etc.)
+	ColBuildId           // GNU build-id note of an ELF file
+	ColInterp            // ELF program interpreter path
+	ColStripped          // true if ELF file has no symbol table
+	ColNeeded            // shared libraries needed by an ELF file
+	ColGitBlob           // git blob object ID
+	ColIntegrity         // result of checking a file's internal checksums
+	ColGrepCount         // number of lines matching the --grep pattern
+	ColGrepLine          // first line matching the --grep pattern
+	ColEntropy           // Shannon entropy of file data in bits per byte
+	ColEntJump           // largest entropy difference from a match on the other side
+	ColAge               // seconds since mtime, relative to run start time
+	ColOtherPath         // path of the entry on the other side a moved file corresponds to
+	ColChange            // kind of change between the entries with the same identity on each side
+	ColChanged           // names of compare key columns that differ from the entry on the other side
+	ColRoot              // the root the file was found under, as given on the command line
+	ColRootIdx           // ordinal of the file's root on the command line
+	ColAbsPath           // absolute path of the file in the file system
+	ColMerge             // three-way merge classification of the entries with the same identity
+	ColMergeAct          // three-way merge action needed to bring the two current sides in sync
 	ColLAST              // dummy end marker; must be last
 
 	// Flag for inverse sort
@@ -99,9 +120,9 @@ func init() {
 	defineColumn("g group     ", ColGroup, "The name of this file's group")
 	defineColumn("L nlinks    ", ColNlinks, "The number of hard links to this file")
 	defineColumn("V device    ", ColDevice, "The ID of the device this file resides on")
-	defineColumn("S side      ", ColSide, "The 'side' of this file's root: '0'=left '1'=right")
-	defineColumn("M matched   ", ColMatched, "True if this file matches any file from the *other* side")
-	defineColumn("m membership", ColMembership, "Visual representation of 'side' and 'matched' columns")
+	defineColumn("S side      ", ColSide, "The 'side' of this file's root: '0'=left '1'=right '2'=third...")
+	defineColumn("M matched   ", ColMatched, "True if this file matches a file on every *other* side")
+	defineColumn("m membership", ColMembership, "Visual representation of 'side', 'matched' and moved status (sides holding the file, like 'AB-', with more than two sides)")
 	defineColumn("r redundancy", ColRedundancy, "Count of files matching this file on *this* side")
 	defineColumn("I redunidx  ", ColRedunIdx, "Ordinal of this file amongst equivalents on *this* side")
 	defineColumn("3 crc32     ", ColCrc32, "The CRC32 digest of this file")
@@ -109,6 +130,27 @@ func init() {
 	defineColumn("2 sha256    ", ColSha256, "The SHA256 digest of this file")
 	defineColumn("A sha512    ", ColSha512, "The SHA512 digest of this file")
 	defineColumn("5 md5       ", ColMd5, "The MD5 digest of this file")
+	defineColumn("E elfarch   ", ColElfArch, "ELF files: the machine architecture, like 'x86_64'")
+	defineColumn("Y elftype   ", ColElfType, "ELF files: exec, pie, shared, relocatable or core")
+	defineColumn("B buildid   ", ColBuildId, "ELF files: the GNU build-id in hex")
+	defineColumn("i interp    ", ColInterp, "ELF files: the program interpreter (dynamic loader)")
+	defineColumn("Z stripped  ", ColStripped, "ELF files: 1 if there is no symbol table, else 0")
+	defineColumn("n needed    ", ColNeeded, "ELF files: comma-separated list of needed shared libraries")
+	defineColumn("H gitblob   ", ColGitBlob, "The git blob object ID of this file")
+	defineColumn("C integrity ", ColIntegrity, "Internal checksum validation: ok, corrupt or unsupported")
+	defineColumn("c grepcount ", ColGrepCount, "The number of lines in this file matching the --grep pattern")
+	defineColumn("l grepline  ", ColGrepLine, "The first line in this file matching the --grep pattern")
+	defineColumn("e entropy   ", ColEntropy, "The Shannon entropy of the file data in bits per byte (0-8)")
+	defineColumn("a age       ", ColAge, "Seconds since this file was modified, as of the run start time")
+	defineColumn("j entropyjump", ColEntJump, "Largest entropy difference from a matching file on the other side")
+	defineColumn("h change    ", ColChange, "With --identity: added, removed, modified, unchanged or moved")
+	defineColumn("k changed   ", ColChanged, "With --identity: the compare key columns that differ from the other side")
+	defineColumn("R root      ", ColRoot, "The root (directory, FSIFT file or git tree) this file was found under")
+	defineColumn("N rootindex ", ColRootIdx, "The ordinal of this file's root on the command line, starting at 1")
+	defineColumn("F abspath   ", ColAbsPath, "The absolute path of this file, if it was scanned in the file system")
+	defineColumn("W merge     ", ColMerge, "With --merge: unchanged, changed-B, changed-C, changed-both or conflict")
+	defineColumn("y mergeaction", ColMergeAct, "With --merge: the action to sync sides B and C, like 'copy:B>C', 'delete:C' or 'conflict'")
+	defineColumn("O counterpart", ColOtherPath, "With --moves: the path of the moved file's counterpart on the other side")
 }
 
 // Return a list of strings holding help text describing all columns
@@ -134,7 +176,8 @@ func (col Column) String() string {
 // Return true if this column holds a numeric (int64) value
 func (col Column) isNumeric() bool {
 	switch col {
-	case ColDepth, ColSize, ColMstamp, ColDevice, ColRedundancy, ColRedunIdx, ColUid, ColGid, ColNlinks, ColSide, ColMatched:
+	case ColDepth, ColSize, ColMstamp, ColDevice, ColRedundancy, ColRedunIdx, ColUid, ColGid, ColNlinks, ColSide, ColMatched,
+		ColStripped, ColGrepCount, ColAge, ColRootIdx:
 		return true
 	default:
 		return false
@@ -144,7 +187,8 @@ func (col Column) isNumeric() bool {
 // Return true if this column is always computed at analyze time and never parsed or scanned
 func (col Column) isDynamic() bool {
 	switch col {
-	case ColSide, ColMatched, ColRedundancy, ColRedunIdx, ColMembership:
+	case ColSide, ColMatched, ColRedundancy, ColRedunIdx, ColMembership, ColEntJump, ColAge, ColOtherPath,
+		ColChange, ColChanged, ColMerge, ColMergeAct:
 		return true
 	default:
 		return false
@@ -203,19 +247,48 @@ func ParseColumnsList(list string, allowInverse bool) ([]Column, error) {
 }
 
 // Pattern to match column directive line in FSIFT file.
-var columnsDirectivePat = regexp.MustCompile(`^\|\s*Columns:\s+([\w,]+)\s*$`)
+var columnsDirectivePat = regexp.MustCompile(`^\|\s*Columns:\s+([\w,:]+)\s*$`)
+
+// Stands for an aggregate field of grouped output in a columns directive,
+// whose values are skipped when loading
+const colAggregate = ColLAST
 
 // Try to parse a line of text as a FSIFT file columns directive.  If it
 // doesn't look like a columns directive, return nil. Otherwise, return the
-// list of any columns and any error parsing the column names.
+// list of any columns and any error parsing the column names. Aggregates
+// of grouped output, like "count" or "sum:size", are returned as
+// colAggregate.
 func parseColumnsDirective(line []byte) ([]Column, error) {
 	match := columnsDirectivePat.FindSubmatch(line)
-	if match != nil {
-		return ParseColumnsList(string(match[1]), false)
-	} else {
+	if match == nil {
 		return nil, nil
 	}
-
+	names := strings.Split(string(match[1]), ",")
+	var colNames []string
+	isAgg := make([]bool, len(names))
+	for i, name := range names {
+		if _, ok := colIndex[name]; !ok && len(names) > 1 {
+			if _, err := ParseAggregates(name); err == nil {
+				isAgg[i] = true
+				continue
+			}
+		}
+		colNames = append(colNames, name)
+	}
+	cols, err := ParseColumnsList(strings.Join(colNames, ","), false)
+	if err != nil {
+		return nil, err
+	}
+	var columns []Column
+	for _, agg := range isAgg {
+		if agg {
+			columns = append(columns, colAggregate)
+		} else {
+			columns = append(columns, cols[0])
+			cols = cols[1:]
+		}
+	}
+	return columns, nil
 }
 
 // Create a comma-separated list of names of the given list of column IDs.
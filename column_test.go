@@ -86,6 +86,8 @@ func Test_parseColumnsDirective(t *testing.T) {
 		{" foo ", nil, ""},                       // not a directive
 		{"| Columns: z", nil, "Bad column name"}, // bad column
 		{"| Columns: p", []Column{ColPath}, ""},  // ok
+		{"| Columns: ext,count,sum:size", []Column{ColExt, colAggregate, colAggregate}, ""}, // grouped
+		{"| Columns: ext,sum:bad", nil, "Bad column name"},
 	}
 	for _, test := range tests {
 		cols, err := parseColumnsDirective([]byte(test.input))
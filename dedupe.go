@@ -0,0 +1,213 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Rules for choosing the copy to keep in each duplicate group with --dedupe
+var keepRules = map[string]bool{"first": true, "oldest": true, "newest": true, "shortest": true}
+
+// Return the index of the copy to keep in a duplicate group by the --keep
+// rule: the first copy in output order, the one with the oldest or newest
+// mtime, or the one with the shortest path. Ties keep the earlier copy.
+func (self *Context) keepIndex(group *dupGroup) int {
+	keep := 0
+	for i, e := range group.entries {
+		k := group.entries[keep]
+		switch self.KeepRule {
+		case "oldest", "newest":
+			diff, notNull := e.compare(k, []Column{ColMtime})
+			self.checkNullCompare(notNull)
+			if diff < 0 && self.KeepRule == "oldest" || diff > 0 && self.KeepRule == "newest" {
+				keep = i
+			}
+		case "shortest":
+			if len(entryPath(e)) < len(entryPath(k)) {
+				keep = i
+			}
+		}
+	}
+	return keep
+}
+
+// Return true if two files have the same content, comparing them byte for
+// byte.
+func sameContent(path1, path2 string) (bool, error) {
+	f1, err := os.Open(path1)
+	if err != nil {
+		return false, err
+	}
+	defer f1.Close()
+	f2, err := os.Open(path2)
+	if err != nil {
+		return false, err
+	}
+	defer f2.Close()
+	buf1 := make([]byte, 64*1024)
+	buf2 := make([]byte, 64*1024)
+	for {
+		n1, err1 := io.ReadFull(f1, buf1)
+		n2, err2 := io.ReadFull(f2, buf2)
+		if !bytes.Equal(buf1[:n1], buf2[:n2]) {
+			return false, nil
+		}
+		// equal short reads mean both files ended
+		if err1 == io.EOF || err1 == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if err1 != nil {
+			return false, err1
+		}
+		if err2 != nil {
+			return false, err2
+		}
+	}
+}
+
+// Replace the file at path with a hard link to keepPath, or with a reflink
+// copy of it that keeps the file's owner, group, mode and mtime. The new
+// file is created next to path, then renamed over it.
+func replaceWithLink(keepPath, path string, info os.FileInfo, reflink bool) error {
+	tmp := path + ".fsift-dedupe"
+	var err error
+	if reflink {
+		err = reflinkFile(keepPath, tmp, info.Mode().Perm())
+		// chown before chmod, since it may clear the setuid and setgid bits
+		if xinfo := statExtended(info); err == nil && xinfo.uidGidValid {
+			err = os.Chown(tmp, int(xinfo.uid), int(xinfo.gid))
+		}
+		if err == nil {
+			err = os.Chmod(tmp, info.Mode())
+		}
+		if err == nil {
+			err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+		}
+	} else {
+		err = os.Link(keepPath, tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil && !os.IsExist(err) {
+		// remove the new file, unless one was already there
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Return true if two files have the same owner, group and mode.
+func samePerms(info1, info2 os.FileInfo) bool {
+	xinfo1, xinfo2 := statExtended(info1), statExtended(info2)
+	return info1.Mode() == info2.Mode() && xinfo1.uid == xinfo2.uid && xinfo1.gid == xinfo2.gid
+}
+
+// Replace a duplicate file with a link to the file being kept, unless they
+// are already the same file or their content differs. Returns the action
+// for the log: the --dedupe method, or "skip:linked", "skip:differs" or
+// "skip:error", or "skip:perms" for a hard link to a file with a different
+// owner, group or mode, which the copy would take on, unless --ignore-perms
+// is set. With --dry-run, the file isn't changed, and the method is prefixed
+// with "would-".
+func (self *Context) dedupeFile(keepPath, path string) string {
+	keepInfo, err := os.Stat(keepPath)
+	if err != nil {
+		self.onError("Error checking file to keep: ", err)
+		return "skip:error"
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		self.onError("Error checking duplicate file: ", err)
+		return "skip:error"
+	}
+	if !info.Mode().IsRegular() {
+		self.onError("Duplicate is not a regular file: ", path)
+		return "skip:error"
+	}
+	if os.SameFile(keepInfo, info) {
+		return "skip:linked"
+	}
+	same, err := sameContent(keepPath, path)
+	if err != nil {
+		self.onError("Error comparing duplicate files: ", err)
+		return "skip:error"
+	}
+	if !same {
+		return "skip:differs"
+	}
+	if self.Dedupe == "hardlink" && !self.IgnorePerms && !samePerms(keepInfo, info) {
+		return "skip:perms"
+	}
+	if self.DryRun {
+		return "would-" + self.Dedupe
+	}
+	if err = replaceWithLink(keepPath, path, info, self.Dedupe == "reflink"); err != nil {
+		self.onError("Error replacing duplicate file: ", err)
+		return "skip:error"
+	}
+	return self.Dedupe
+}
+
+// Deduplicate a group: replace each copy but the one chosen by --keep with a
+// link to it, and add a log line for each copy to the group. The log line
+// has the action, the kept path and the copy's path, escaped like the
+// non-last columns of an FSIFT file. Updates the dedupe statistics, which
+// count the copies that would be replaced with --dry-run.
+func (self *Context) dedupeGroup(group *dupGroup) {
+	keep := self.keepIndex(group)
+	keepPath, keepOk := group.entries[keep].getStringField(ColAbsPath)
+	for i, e := range group.entries {
+		if i == keep {
+			continue
+		}
+		path, ok := e.getStringField(ColAbsPath)
+		action := "skip:error"
+		if !keepOk || !ok {
+			self.onError("Can't deduplicate files without an absolute path: ", entryPath(e))
+		} else {
+			action = self.dedupeFile(keepPath, path)
+		}
+		if !strings.HasPrefix(action, "skip:") {
+			self.dedupeStats.update(group.side, e.getNumericFieldOrZero(ColSize))
+		}
+		group.log = append(group.log, fmt.Sprintf("%s %s %s", action,
+			escapeField(keepPath, keepOk, false), escapeField(path, ok, false)))
+	}
+}
+
+// Write the dedupe log lines of all groups to the --dedupe-log file.
+func (self *Context) writeDedupeLog(groups []*dupGroup) {
+	var lines []string
+	for _, group := range groups {
+		lines = append(lines, group.log...)
+	}
+	var text string
+	if len(lines) > 0 {
+		text = strings.Join(lines, "\n") + "\n"
+	}
+	if err := ioutil.WriteFile(self.DedupeLog, []byte(text), 0666); err != nil {
+		self.onError("Error writing dedupe log: ", err)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Context_keepIndex(t *testing.T) {
+	group := &dupGroup{entries: []fileEntry{
+		{ColPath: "b/ccc", ColMtime: "2017-01-02T00:00:00Z"},
+		{ColPath: "a/bb", ColMtime: "2017-01-03T00:00:00Z"},
+		{ColPath: "a/b", ColMtime: "2017-01-01T00:00:00Z"},
+		{ColPath: "c/d", ColMtime: "2017-01-03T00:00:00Z"},
+	}}
+	var tests = []struct {
+		rule string
+		want int
+	}{
+		{"first", 0}, {"oldest", 2}, {"newest", 1}, {"shortest", 2},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.KeepRule = test.rule
+		checkVal(t, test.want, ctx.keepIndex(group))
+	}
+}
+
+func Test_sameContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-dedupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	big := make([]byte, 200000)
+	big[150000] = 1
+	files := map[string][]byte{
+		"empty": nil, "a": []byte("abc"), "b": []byte("abc"), "c": []byte("abd"),
+		"d": []byte("abcd"), "big1": big, "big2": append([]byte{}, big...),
+		"big3": make([]byte, 200000),
+	}
+	for name, data := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), data, 0666)
+	}
+	var tests = []struct {
+		path1, path2 string
+		want         bool
+		err          string
+	}{
+		{"a", "b", true, ""},
+		{"a", "c", false, ""},
+		{"a", "d", false, ""},
+		{"d", "a", false, ""},
+		{"empty", "empty", true, ""},
+		{"empty", "a", false, ""},
+		{"big1", "big2", true, ""},
+		{"big1", "big3", false, ""},
+		{"a", "none", false, "open "},
+	}
+	for _, test := range tests {
+		got, err := sameContent(filepath.Join(dir, test.path1), filepath.Join(dir, test.path2))
+		checkValErr1(t, test.want, got, test.err, err)
+	}
+}
+
+func Test_Context_dedupeGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-dedupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for _, name := range []string{"a", "b", "c"} {
+		ioutil.WriteFile(path(name), []byte("same"), 0666)
+	}
+	ioutil.WriteFile(path("d"), []byte("diff"), 0666)
+	os.Link(path("a"), path("e"))
+	var entries []fileEntry
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		entries = append(entries, fileEntry{ColPath: name, ColAbsPath: path(name), ColSize: int64(4)})
+	}
+	entries = append(entries, fileEntry{ColPath: "f", ColSize: int64(4)})
+
+	for _, dryRun := range []bool{true, false} {
+		ctx := NewContext()
+		ctx.Dedupe = "hardlink"
+		ctx.DryRun = dryRun
+		group := &dupGroup{entries: entries}
+		ctx.dedupeGroup(group)
+		action := "hardlink "
+		if dryRun {
+			action = "would-hardlink "
+		}
+		checkVal(t, []string{
+			action + path("a") + " " + path("b"),
+			action + path("a") + " " + path("c"),
+			"skip:differs " + path("a") + " " + path("d"),
+			"skip:linked " + path("a") + " " + path("e"),
+			"skip:error " + path("a") + ` \~`,
+		}, group.log)
+		c, s := ctx.dedupeStats.get(0)
+		checkVal(t, []int64{2, 8}, []int64{c, s})
+		checkVal(t, 1, ctx.errorCount)
+
+		info1, _ := os.Stat(path("a"))
+		info2, _ := os.Stat(path("c"))
+		checkVal(t, !dryRun, os.SameFile(info1, info2))
+	}
+	_, err = os.Stat(path("b.fsift-dedupe"))
+	checkVal(t, true, os.IsNotExist(err))
+}
+
+func Test_Context_dedupeFile_perms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-dedupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for _, name := range []string{"a", "b"} {
+		ioutil.WriteFile(path(name), []byte("same"), 0666)
+	}
+	os.Chmod(path("a"), 0644)
+	os.Chmod(path("b"), 0600)
+
+	ctx := NewContext()
+	ctx.Dedupe = "hardlink"
+	checkVal(t, "skip:perms", ctx.dedupeFile(path("a"), path("b")))
+	ctx.IgnorePerms = true
+	checkVal(t, "hardlink", ctx.dedupeFile(path("a"), path("b")))
+	info, _ := os.Stat(path("b"))
+	checkVal(t, os.FileMode(0644), info.Mode())
+}
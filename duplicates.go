@@ -0,0 +1,203 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"sort"
+	"strings"
+)
+
+// A group of files on one side with the same compare key, for --duplicates.
+type dupGroup struct {
+	entries []fileEntry // the copies, in output order
+	side    int         // the side the copies are on
+	log     []string    // the --dedupe log lines for the copies
+}
+
+// Return the index of the largest copy in a group, the first if several are
+// the largest.
+func (self *dupGroup) largest() int {
+	max := 0
+	for i, e := range self.entries {
+		if e.getNumericFieldOrZero(ColSize) > self.entries[max].getNumericFieldOrZero(ColSize) {
+			max = i
+		}
+	}
+	return max
+}
+
+// Return the size of the largest copy in a group.
+func (self *dupGroup) size() int64 {
+	return self.entries[self.largest()].getNumericFieldOrZero(ColSize)
+}
+
+// Return the bytes that would be freed by keeping only the largest copy.
+func (self *dupGroup) reclaimable() int64 {
+	var total int64
+	for _, e := range self.entries {
+		total += e.getNumericFieldOrZero(ColSize)
+	}
+	return total - self.size()
+}
+
+// Find the groups of files (not directories) with the same compare key on
+// each side among the output entries, and with more than one copy. Groups
+// are sorted by reclaimable bytes, largest first. Copies in each group, and
+// groups that reclaim the same bytes, are in --sort order, or path order.
+func (self *Context) findDuplicates(entries []fileEntry) []*dupGroup {
+	var files []fileEntry
+	for _, e := range entries {
+		if !strings.HasSuffix(entryPath(e), "/") {
+			files = append(files, e)
+		}
+	}
+	// find the match groups like analyzeMatches
+	sorter := newEntrySorter(self, files, self.matchSortCols())
+	sorter.keys = self.mapKeyEntries(files)
+	sort.Sort(sorter)
+	ids := self.findMatchGroups(files, sorter.keys)
+
+	// split each match group by side
+	type dupKey struct{ id, side int }
+	index := map[dupKey]*dupGroup{}
+	var groups []*dupGroup
+	for i, e := range files {
+		key := dupKey{ids[i], sideIndex(e)}
+		group := index[key]
+		if group == nil {
+			group = &dupGroup{side: key.side}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.entries = append(group.entries, e)
+	}
+
+	orderCols := self.SortCols.cols
+	if len(orderCols) == 0 {
+		orderCols = []Column{ColPath}
+	}
+	var dups []*dupGroup
+	for _, group := range groups {
+		if len(group.entries) > 1 {
+			sort.Stable(newEntrySorter(self, group.entries, orderCols))
+			dups = append(dups, group)
+		}
+	}
+	sort.SliceStable(dups, func(i, j int) bool {
+		if r1, r2 := dups[i].reclaimable(), dups[j].reclaimable(); r1 != r2 {
+			return r1 > r2
+		}
+		diff, _ := dups[i].entries[0].compare(dups[j].entries[0], orderCols)
+		return diff < 0
+	})
+	return dups
+}
+
+// Output the duplicate groups among the filtered entries as blocks, each
+// after a header line with the number of copies and reclaimable bytes, and
+// update the output and duplicate statistics. With --dedupe, deduplicate each
+// group first. With --fdupes, output the
+// paths of each group on their own lines, then a blank line, like fdupes.
+func (self *Context) outputDuplicates(entries []fileEntry, indent, separator string) {
+	groups := self.findDuplicates(entries)
+	for _, group := range groups {
+		// all copies but the largest could be reclaimed
+		largest := group.largest()
+		for i, e := range group.entries {
+			size := e.getNumericFieldOrZero(ColSize)
+			self.duplicateStats.update(group.side, size)
+			if i != largest {
+				self.reclaimStats.update(group.side, size)
+			}
+		}
+	}
+	if self.Dedupe != "" {
+		for _, group := range groups {
+			self.dedupeGroup(group)
+		}
+		if self.DedupeLog != "" {
+			self.writeDedupeLog(groups)
+		}
+	}
+	if self.SummaryOnly {
+		return
+	}
+	if self.Fdupes {
+		for _, group := range groups {
+			for _, e := range group.entries {
+				self.updateOutputStats(e)
+				p, ok := e.getStringField(ColAbsPath)
+				if !ok {
+					p = entryPath(e)
+				}
+				self.outf(-1, "%s", p)
+			}
+			self.outf(-1, "")
+		}
+		return
+	}
+
+	// format the entry lines of all groups, so they're padded alike
+	var numeric []bool
+	for _, col := range self.OutCols.cols {
+		numeric = append(numeric, col.isNumeric())
+	}
+	nCols := len(self.OutCols.cols)
+	var lines [][]string
+	for _, group := range groups {
+		for _, e := range group.entries {
+			var fields []string
+			for i, col := range self.OutCols.cols {
+				fields = append(fields, e.formatField(self, col, -1, i >= nCols-1 || self.Plain0))
+			}
+			lines = append(lines, fields)
+		}
+	}
+	self.padTable(lines, numeric)
+
+	for n, group := range groups {
+		if self.Plain {
+			if n > 0 {
+				self.outf(-1, "")
+			}
+		} else {
+			if n > 0 {
+				self.headerOut("")
+			}
+			side := ""
+			if self.numSides() > 1 {
+				side = " (" + self.sideName(group.side) + ")"
+			}
+			self.headerOut("Duplicate group %d%s: %s copies of %s bytes, %s bytes reclaimable", n+1, side,
+				self.formatNumber(int64(len(group.entries))), self.formatNumber(group.size()),
+				self.formatNumber(group.reclaimable()))
+			if self.DedupeLog == "" {
+				// without a log file, show the dedupe log with each group
+				for _, line := range group.log {
+					self.headerOut("%s", line)
+				}
+			}
+		}
+		for _, e := range group.entries {
+			self.updateOutputStats(e)
+			self.outf(-1, indent+"%s", strings.Join(lines[0], separator))
+			lines = lines[1:]
+		}
+	}
+}
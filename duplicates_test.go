@@ -0,0 +1,70 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_Context_findDuplicates(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols.cols = []Column{ColSize, ColMd5}
+	entries := []fileEntry{
+		{ColPath: "d/", ColSize: int64(4), ColMd5: "x"},
+		{ColPath: "c", ColSize: int64(4), ColMd5: "x"},
+		{ColPath: "a", ColSize: int64(4), ColMd5: "x"},
+		{ColPath: "u", ColSize: int64(9), ColMd5: "u"},
+		{ColPath: "e", ColSize: int64(9), ColMd5: "y"},
+		{ColPath: "f", ColSize: int64(9), ColMd5: "y"},
+		{ColPath: "g", ColSize: int64(4), ColMd5: "z", ColSide: int64(1)},
+		{ColPath: "h", ColSize: int64(4), ColMd5: "z", ColSide: int64(1)},
+		{ColPath: "b", ColSize: int64(4), ColMd5: "x"},
+		{ColPath: "i", ColSize: int64(4), ColMd5: "x", ColSide: int64(1)},
+	}
+	var got [][]string
+	var reclaim []int64
+	for _, group := range ctx.findDuplicates(entries) {
+		var paths []string
+		for _, e := range group.entries {
+			paths = append(paths, entryPath(e))
+		}
+		got = append(got, paths)
+		reclaim = append(reclaim, group.reclaimable())
+	}
+	checkVal(t, [][]string{{"e", "f"}, {"a", "b", "c"}, {"g", "h"}}, got)
+	checkVal(t, []int64{9, 8, 4}, reclaim)
+
+	ctx.SummaryOnly = true
+	ctx.outputDuplicates(entries, "", "")
+	c, s := ctx.duplicateStats.get(0)
+	checkVal(t, []int64{5, 30}, []int64{c, s})
+	c, s = ctx.reclaimStats.get(0)
+	checkVal(t, []int64{3, 17}, []int64{c, s})
+	c, s = ctx.reclaimStats.get(1)
+	checkVal(t, []int64{1, 4}, []int64{c, s})
+}
+
+func Test_dupGroup_reclaimable(t *testing.T) {
+	group := dupGroup{entries: []fileEntry{
+		{ColSize: int64(3)}, {ColSize: int64(5)}, {}, {ColSize: int64(5)},
+	}}
+	checkVal(t, 1, group.largest())
+	checkVal(t, int64(5), group.size())
+	checkVal(t, int64(8), group.reclaimable())
+}
@@ -0,0 +1,212 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// The columns that are computed by parsing ELF headers
+var elfColumns = []Column{ColElfArch, ColElfType, ColBuildId, ColInterp, ColStripped, ColNeeded}
+
+// Magic number at the start of every ELF file
+const elfMagic = "\x7fELF"
+
+// Note type of a GNU build-id note
+const ntGnuBuildId = 3
+
+// Return true if any of the ELF columns are needed for this run
+func (self *Context) needsElfCols() bool {
+	for _, col := range elfColumns {
+		if self.needsCol(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return a short name for the ELF object type. Position independent
+// executables are shared objects that request a program interpreter.
+func elfTypeName(f *elf.File, interp string) string {
+	switch f.Type {
+	case elf.ET_EXEC:
+		return "exec"
+	case elf.ET_DYN:
+		if interp != "" {
+			return "pie"
+		}
+		return "shared"
+	case elf.ET_REL:
+		return "relocatable"
+	case elf.ET_CORE:
+		return "core"
+	default:
+		return strings.ToLower(strings.TrimPrefix(f.Type.String(), "ET_"))
+	}
+}
+
+// Return the path of the program interpreter requested by an ELF file, or an
+// empty string if there is none.
+func elfInterp(f *elf.File) string {
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			data, err := ioutil.ReadAll(prog.Open())
+			if err == nil {
+				return string(bytes.TrimRight(data, "\x00"))
+			}
+		}
+	}
+	return ""
+}
+
+// Search the note data of an ELF file for a GNU build-id note and return it as
+// a hex string, or an empty string if there is none.
+func elfBuildId(f *elf.File) string {
+	var notes [][]byte
+	for _, sect := range f.Sections {
+		if sect.Type == elf.SHT_NOTE {
+			if data, err := sect.Data(); err == nil {
+				notes = append(notes, data)
+			}
+		}
+	}
+	if len(notes) == 0 {
+		// stripped section headers; fall back to note segments
+		for _, prog := range f.Progs {
+			if prog.Type == elf.PT_NOTE {
+				if data, err := ioutil.ReadAll(prog.Open()); err == nil {
+					notes = append(notes, data)
+				}
+			}
+		}
+	}
+	for _, data := range notes {
+		if id := parseBuildIdNote(data, f.ByteOrder); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// Parse a block of ELF notes, returning the hex value of any GNU build-id
+// note found. Each note is a header of name size, descriptor size and type,
+// followed by the name and descriptor, each padded to 4 bytes.
+func parseBuildIdNote(data []byte, order binary.ByteOrder) string {
+	align := func(n uint32) int { return int((n + 3) &^ 3) }
+	for len(data) >= 12 {
+		nameSize := order.Uint32(data[0:4])
+		descSize := order.Uint32(data[4:8])
+		noteType := order.Uint32(data[8:12])
+		data = data[12:]
+		if align(nameSize) > len(data) {
+			break
+		}
+		name := data[:nameSize]
+		data = data[align(nameSize):]
+		if align(descSize) > len(data) {
+			break
+		}
+		desc := data[:descSize]
+		data = data[align(descSize):]
+		if noteType == ntGnuBuildId && string(name) == "GNU\x00" {
+			return hex.EncodeToString(desc)
+		}
+	}
+	return ""
+}
+
+// Compute the needed ELF columns for a file by reading its headers. Files
+// that are not regular or aren't ELF objects get empty values for the string
+// columns (like the digest columns) and no value for 'stripped'.
+func (self *Context) calcElfFile(root string, entry fileEntry) {
+	// get the file name and open it
+	relPath, ok := entry.getStringField(ColPath)
+	if !ok {
+		self.onError("Missing path in file entry: ")
+		return
+	}
+	filePath := myJoin(root, relPath)
+	setEmpty := func() {
+		for _, col := range elfColumns {
+			if self.needsCol(col) && !col.isNumeric() {
+				entry.setStringField(col, "")
+			}
+		}
+	}
+	fi, err := self.statFile(filePath)
+	if err != nil {
+		self.onError("Can't get file information: ", err)
+		return
+	}
+	if !fi.Mode().IsRegular() {
+		setEmpty()
+		return
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		self.onError("Can't open file for reading: ", err)
+		return
+	}
+	defer file.Close()
+
+	// check the magic number first so other files don't generate errors
+	magic := make([]byte, len(elfMagic))
+	if _, err = io.ReadFull(file, magic); err != nil || string(magic) != elfMagic {
+		setEmpty()
+		return
+	}
+	f, err := elf.NewFile(file)
+	if err != nil {
+		self.onError("Can't parse ELF file: ", filePath, ": ", err)
+		setEmpty()
+		return
+	}
+	defer f.Close()
+
+	self.outTempf(0, "ELF %s", filePath)
+	interp := elfInterp(f)
+	for _, col := range elfColumns {
+		if !self.needsCol(col) {
+			continue
+		}
+		switch col {
+		case ColElfArch:
+			arch := strings.TrimPrefix(f.Machine.String(), "EM_")
+			entry.setStringField(col, strings.ToLower(arch))
+		case ColElfType:
+			entry.setStringField(col, elfTypeName(f, interp))
+		case ColBuildId:
+			entry.setStringField(col, elfBuildId(f))
+		case ColInterp:
+			entry.setStringField(col, interp)
+		case ColStripped:
+			entry.setBoolField(col, f.Section(".symtab") == nil)
+		case ColNeeded:
+			libs, _ := f.ImportedLibraries()
+			entry.setStringField(col, strings.Join(libs, ","))
+		}
+	}
+}
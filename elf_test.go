@@ -0,0 +1,110 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func Test_parseBuildIdNote(t *testing.T) {
+	le := binary.LittleEndian
+	note := func(name string, typ uint32, desc []byte) []byte {
+		out := make([]byte, 12)
+		le.PutUint32(out[0:], uint32(len(name)))
+		le.PutUint32(out[4:], uint32(len(desc)))
+		le.PutUint32(out[8:], typ)
+		out = append(out, name...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+		out = append(out, desc...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	var tests = []struct {
+		data []byte
+		want string
+	}{
+		{nil, ""}, // empty
+		{note("GNU\x00", ntGnuBuildId, []byte{0xde, 0xad, 0xbe, 0xef}), "deadbeef"},                        // build-id
+		{note("GNU\x00", 1, []byte{1, 2, 3, 4}), ""},                                                       // other type
+		{append(note("Go\x00", 4, []byte{1, 2, 3}), note("GNU\x00", ntGnuBuildId, []byte{0xab})...), "ab"}, // second note
+		{note("GNU\x00", ntGnuBuildId, []byte{1, 2, 3, 4})[:18], ""},                                       // truncated
+	}
+	for _, test := range tests {
+		checkVal(t, test.want, parseBuildIdNote(test.data, le))
+	}
+}
+
+func Test_Context_calcElfFile(t *testing.T) {
+	ctx := NewContext()
+	for _, col := range elfColumns {
+		ctx.neededCols[col] = true
+	}
+	checkVal(t, true, ctx.needsElfCols())
+
+	// a file that isn't ELF gets empty strings
+	f1, err := ioutil.TempFile("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp file for unit test")
+		return
+	}
+	defer func() { os.Remove(f1.Name()) }()
+	_, err = f1.WriteString("foo")
+	if err != nil {
+		t.Error("Couln't write to temp file for unit test")
+		return
+	}
+	f1.Close()
+	ef1 := fileEntry{ColPath: f1.Name()}
+	ctx.calcElfFile("", ef1)
+	want := fileEntry{
+		ColPath:    f1.Name(),
+		ColElfArch: "",
+		ColElfType: "",
+		ColBuildId: "",
+		ColInterp:  "",
+		ColNeeded:  "",
+	}
+	checkVal(t, want, ef1)
+
+	// the test program itself is an ELF executable on most unix systems
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" {
+		return
+	}
+	ef2 := fileEntry{ColPath: os.Args[0]}
+	ctx.calcElfFile("", ef2)
+	elfType, _ := ef2.getStringField(ColElfType)
+	if elfType != "exec" && elfType != "pie" {
+		t.Errorf("Unexpected ELF type '%s' for test executable", elfType)
+	}
+	arch, _ := ef2.getStringField(ColElfArch)
+	if arch == "" {
+		t.Error("Expected an ELF architecture for test executable")
+	}
+	_, ok := ef2.getBoolField(ColStripped)
+	checkVal(t, true, ok)
+	checkVal(t, []string(nil), ctx.errorMessages)
+}
@@ -0,0 +1,116 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Accumulates a histogram of the byte values written to it, for computing
+// Shannon entropy. If limit is greater than zero, only that many leading
+// bytes are counted.
+type entropyCounter struct {
+	counts [256]int64 // number of occurrences of each byte value
+	total  int64      // number of bytes counted
+	limit  int64      // max bytes to count, or zero for no limit
+}
+
+// Count the bytes in p, implementing io.Writer so the counter can be fed
+// alongside the digest algorithms.
+func (self *entropyCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	if self.limit > 0 && self.total+int64(len(p)) > self.limit {
+		p = p[:self.limit-self.total]
+	}
+	for _, b := range p {
+		self.counts[b]++
+	}
+	self.total += int64(len(p))
+	return n, nil
+}
+
+// Return the Shannon entropy of the counted bytes in bits per byte, from
+// 0 (empty or constant data) to 8 (uniformly random data).
+func (self *entropyCounter) entropy() float64 {
+	if self.total == 0 {
+		return 0
+	}
+	e := 0.0
+	for _, n := range self.counts {
+		if n > 0 {
+			p := float64(n) / float64(self.total)
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// Format an entropy value for the entropy columns. A fixed number of decimals
+// (with a single integer digit) means values compare correctly as strings.
+func formatEntropy(e float64) string {
+	return fmt.Sprintf("%.3f", e)
+}
+
+// For each entry in a match group, set the entropyjump column to the largest
+// absolute difference between its entropy and the entropy of an entry on any
+// other side of the group. Entries with nothing to compare against get an
+// empty string.
+func setEntropyJumps(group []fileEntry) {
+	// find the range of entropy values on each side
+	lo, hi := map[int]float64{}, map[int]float64{}
+	found := map[int]bool{}
+	values := make([]float64, len(group))
+	valid := make([]bool, len(group))
+	for i, entry := range group {
+		s, ok := entry.getStringField(ColEntropy)
+		if !ok || s == "" {
+			continue
+		}
+		e, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			continue
+		}
+		side := sideIndex(entry)
+		if !found[side] || e < lo[side] {
+			lo[side] = e
+		}
+		if !found[side] || e > hi[side] {
+			hi[side] = e
+		}
+		found[side] = true
+		values[i], valid[i] = e, true
+	}
+	for i, entry := range group {
+		side := sideIndex(entry)
+		jump, compared := 0.0, false
+		for other := range found {
+			if other != side && valid[i] {
+				jump = math.Max(jump, math.Max(math.Abs(values[i]-lo[other]), math.Abs(values[i]-hi[other])))
+				compared = true
+			}
+		}
+		if !compared {
+			entry.setStringField(ColEntJump, "")
+			continue
+		}
+		entry.setStringField(ColEntJump, formatEntropy(jump))
+	}
+}
@@ -0,0 +1,111 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_entropyCounter(t *testing.T) {
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+	var tests = []struct {
+		data  []byte
+		limit int64
+		want  string
+	}{
+		{nil, 0, "0.000"},
+		{[]byte("aaaa"), 0, "0.000"},
+		{[]byte("abab"), 0, "1.000"},
+		{[]byte("abcd"), 0, "2.000"},
+		{[]byte("aaab"), 0, "0.811"},
+		{allBytes, 0, "8.000"},
+		{[]byte("aaaabcd"), 4, "0.000"}, // only the sample is counted
+	}
+	for _, test := range tests {
+		counter := entropyCounter{limit: test.limit}
+		// write in two pieces to check the limit across calls
+		n1, _ := counter.Write(test.data[:len(test.data)/2])
+		n2, _ := counter.Write(test.data[len(test.data)/2:])
+		checkVal(t, len(test.data), n1+n2)
+		checkVal(t, test.want, formatEntropy(counter.entropy()))
+	}
+}
+
+func Test_setEntropyJumps(t *testing.T) {
+	group := []fileEntry{
+		{ColSide: int64(0), ColEntropy: "4.000"},
+		{ColSide: int64(0), ColEntropy: "5.500"},
+		{ColSide: int64(1), ColEntropy: "7.900"},
+		{ColSide: int64(1), ColEntropy: ""},
+	}
+	setEntropyJumps(group)
+	var want = []string{"3.900", "2.400", "3.900", ""}
+	for i, entry := range group {
+		got, _ := entry.getStringField(ColEntJump)
+		checkVal(t, want[i], got)
+	}
+
+	// no entries on the other side
+	group = []fileEntry{{ColSide: int64(0), ColEntropy: "4.000"}}
+	setEntropyJumps(group)
+	got, _ := group[0].getStringField(ColEntJump)
+	checkVal(t, "", got)
+}
+
+func Test_Context_calcDigestFile_entropy(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	ioutil.WriteFile(filepath.Join(dirPath, "a"), []byte("aaaabcd"), 0644)
+
+	var tests = []struct {
+		path    string
+		sample  int64
+		withMd5 bool
+		want    string
+	}{
+		{"a", 0, false, "1.664"},
+		{"a", 4, false, "0.000"},
+		{"a", 4, true, "0.000"}, // sample still applies when reading the whole file
+		{".", 0, false, ""},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.EntropySample = test.sample
+		ctx.neededCols[ColEntropy] = true
+		ctx.neededCols[ColMd5] = test.withMd5
+		entry := fileEntry{ColPath: test.path}
+		ctx.calcDigestFile(dirPath, entry)
+		got, _ := entry.getStringField(ColEntropy)
+		checkVal(t, test.want, got)
+		if test.withMd5 {
+			md5, _ := entry.getStringField(ColMd5)
+			checkVal(t, "29d793a35a3f76370566636c098bbafc", md5)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Pattern to match the column name and operator of a comparison within a
+// filter expression. The value is scanned separately.
+var exprComparePat = regexp.MustCompile(`^(\w+)\s*(` + filterOpPat + `)\s*`)
+
+// Holds the state of a filter expression being parsed. The grammar, from
+// lowest to highest precedence, is:
+//
+//	expr     := and-expr { "or" and-expr }
+//	and-expr := not-expr { "and" not-expr }
+//	not-expr := "not" not-expr | "(" expr ")" | COLUMN OP VALUE
+type exprParser struct {
+	input string // the whole expression
+	pos   int    // current position in input
+}
+
+// ParseFilterExpr parses an infix filter expression like
+// "(size>1000 and ext=.log) or not user=root" and returns a filter tree
+// implementing it, or an error if it couldn't be parsed.
+func ParseFilterExpr(expr string) (*Filter, error) {
+	p := exprParser{input: expr}
+	filt, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected text")
+	}
+	return filt, nil
+}
+
+// Return an error describing a problem at the current position.
+func (self *exprParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Bad filter expression: %s at position %d: '%s'",
+		fmt.Sprintf(format, a...), self.pos+1, self.input)
+}
+
+// Advance past any white space.
+func (self *exprParser) skipSpace() {
+	for self.pos < len(self.input) && unicode.IsSpace(rune(self.input[self.pos])) {
+		self.pos++
+	}
+}
+
+// If the next token is the given keyword (case insensitive), consume it and
+// return true.
+func (self *exprParser) keyword(word string) bool {
+	self.skipSpace()
+	end := self.pos + len(word)
+	if end > len(self.input) || !strings.EqualFold(self.input[self.pos:end], word) {
+		return false
+	}
+	if end < len(self.input) {
+		// keyword must not run into a longer word
+		c := self.input[end]
+		if c != '(' && c != ')' && !unicode.IsSpace(rune(c)) {
+			return false
+		}
+	}
+	self.pos = end
+	return true
+}
+
+// Parse a sequence of terms joined by "or".
+func (self *exprParser) parseOr() (*Filter, error) {
+	left, err := self.parseAnd()
+	for err == nil && self.keyword("or") {
+		var right *Filter
+		right, err = self.parseAnd()
+		left = &Filter{op: opOr, left: left, right: right}
+	}
+	return left, err
+}
+
+// Parse a sequence of terms joined by "and".
+func (self *exprParser) parseAnd() (*Filter, error) {
+	left, err := self.parseNot()
+	for err == nil && self.keyword("and") {
+		var right *Filter
+		right, err = self.parseNot()
+		left = &Filter{op: opAnd, left: left, right: right}
+	}
+	return left, err
+}
+
+// Parse a possibly negated parenthesized expression or comparison.
+func (self *exprParser) parseNot() (*Filter, error) {
+	if self.keyword("not") {
+		filt, err := self.parseNot()
+		if err == nil {
+			filt.not = !filt.not
+		}
+		return filt, err
+	}
+	self.skipSpace()
+	if strings.HasPrefix(self.input[self.pos:], "(") {
+		self.pos++
+		filt, err := self.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		self.skipSpace()
+		if !strings.HasPrefix(self.input[self.pos:], ")") {
+			return nil, self.errorf("missing ')'")
+		}
+		self.pos++
+		return filt, nil
+	}
+	return self.parseCompare()
+}
+
+// Parse a comparison of a column with a value.
+func (self *exprParser) parseCompare() (*Filter, error) {
+	parts := exprComparePat.FindStringSubmatch(self.input[self.pos:])
+	if parts == nil {
+		return nil, self.errorf("expected a comparison")
+	}
+	self.pos += len(parts[0])
+	value := ""
+	if !strings.HasSuffix(parts[2], ".isnull") {
+		var err error
+		value, err = self.parseValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newCompareFilter(parts[1], parts[2], value)
+}
+
+// Parse a comparison value. Values may be quoted with single or double
+// quotes; otherwise they end at white space or a ')'.
+func (self *exprParser) parseValue() (string, error) {
+	rest := self.input[self.pos:]
+	if strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`) {
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return "", self.errorf("missing closing quote")
+		}
+		self.pos += end + 2
+		return rest[1 : end+1], nil
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool { return r == ')' || unicode.IsSpace(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+	self.pos += end
+	return rest[:end], nil
+}
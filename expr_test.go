@@ -0,0 +1,126 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Format a filter tree in a compact form for checking parse results
+func describeFilter(f *Filter) string {
+	if f == nil {
+		return "nil"
+	}
+	not := ""
+	if f.not {
+		not = "!"
+	}
+	switch f.op {
+	case opAnd:
+		return not + "and(" + describeFilter(f.left) + "," + describeFilter(f.right) + ")"
+	case opOr:
+		return not + "or(" + describeFilter(f.left) + "," + describeFilter(f.right) + ")"
+	}
+	ops := map[filtOp]string{opEq: "=", opLess: "<", opLessEq: "<=", opRegex: "~=", opGlob: "*=", opIsNull: ".isnull", opIn: " in "}
+	value := ""
+	switch v := f.value.(type) {
+	case string:
+		value = v
+	case int64:
+		value = "#" + strconv.FormatInt(v, 10)
+	}
+	return not + f.column.String() + ops[f.op] + value
+}
+
+func Test_ParseFilterExpr(t *testing.T) {
+	var tests = []struct {
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{"size>10", "!size<=#10", ""},
+		{"  ext = .log  ", "ext=.log", ""},
+		{"b=1 and x=2 or D=3", "or(and(base=1,ext=2),dir=3)", ""},
+		{"b=1 or x=2 and D=3", "or(base=1,and(ext=2,dir=3))", ""},
+		{"b=1 AND (x=2 OR D=3)", "and(base=1,or(ext=2,dir=3))", ""},
+		{"not b=1", "!base=1", ""},
+		{"not b!=1", "base=1", ""},
+		{"not (b=1 or x=2)", "!or(base=1,ext=2)", ""},
+		{"not(b=1)", "!base=1", ""},
+		{"(((p=x)))", "path=x", ""},
+		{"p *= '*.log' and x ~= \"a b\"", "and(path*=*.log,ext~=a b)", ""},
+		{"p='' or u.isnull", "or(path=,user.isnull)", ""},
+		{"p=x)", "", "Bad filter expression: unexpected text at position 4"},
+		{"(p=x", "", "Bad filter expression: missing ')' at position 5"},
+		{"p='x", "", "Bad filter expression: missing closing quote at position 3"},
+		{"p=x and", "", "Bad filter expression: expected a comparison at position 8"},
+		{"notes=1", "", "Bad column name in filter: 'notes'"},
+		{"and=1", "", "Bad column name in filter: 'and'"},
+		{"", "", "Bad filter expression: expected a comparison at position 1"},
+		{"s=x", "", "strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		got, err := ParseFilterExpr(test.expr)
+		if err == nil {
+			checkValErr1(t, test.want, describeFilter(got), test.wantErr, err)
+		} else {
+			checkValErr1(t, nil, nil, test.wantErr, err)
+		}
+	}
+}
+
+func Test_Filter_filter_expr(t *testing.T) {
+	entry := fileEntry{ColPath: "a/b.log", ColSize: int64(100), ColExt: ".log"}
+	var tests = []struct {
+		expr      string
+		wantMatch bool
+		wantOk    bool
+	}{
+		{"size>50 and ext=.log", true, true},
+		{"size>500 and ext=.log", false, true},
+		{"not (size>500 and ext=.log)", true, true},
+		{"size>500 or ext=.log", true, true},
+		{"not (size>500 or ext=.log)", false, true},
+		{"user=root or size>50", false, false}, // null compare
+		{"not (user=root or size>50)", false, false},
+		{"size>50 or user=root", true, true}, // short circuit skips null
+	}
+	for _, test := range tests {
+		filt, err := ParseFilterExpr(test.expr)
+		checkValErr1(t, nil, nil, "", err)
+		match, ok := filt.filter(entry)
+		checkVal(t, test.wantMatch, match)
+		checkVal(t, test.wantOk, ok)
+	}
+}
+
+func Test_compileFilter_expr(t *testing.T) {
+	// an expression tree mixed with forward Polish args is left intact
+	expr, _ := ParseFilterExpr("b=1 or x=2")
+	or, _ := ParseFilter("or")
+	c, _ := ParseFilter("D=3")
+	d, _ := ParseFilter("d=4")
+	got, err := compileFilter([]*Filter{or, expr, c, d})
+	checkValErr1(t, "and(or(or(base=1,ext=2),dir=3),depth=#4)", describeFilter(got), "", err)
+
+	cols := map[Column]bool{}
+	got.addColumns(cols)
+	checkVal(t, map[Column]bool{ColBase: true, ColExt: true, ColDir: true, ColDepth: true}, cols)
+}
@@ -34,6 +34,7 @@ type entrySorter struct {
 	ctx     *Context    // the context the entry belongs to
 	entries []fileEntry // the entries to sort
 	columns []Column    // the columns to sort by from highest to lowest precedence
+	keys    []fileEntry // if set, entries to compare in place of the sorted entries
 }
 
 // Create an entry sorter for the given entries and key columns
@@ -53,10 +54,17 @@ func (self *entrySorter) Len() int {
 
 func (self *entrySorter) Swap(i, j int) {
 	self.entries[i], self.entries[j] = self.entries[j], self.entries[i]
+	if self.keys != nil {
+		self.keys[i], self.keys[j] = self.keys[j], self.keys[i]
+	}
 }
 
 func (self *entrySorter) Less(i, j int) bool {
-	diff, notNull := self.entries[i].compare(self.entries[j], self.columns)
+	entries := self.entries
+	if self.keys != nil {
+		entries = self.keys
+	}
+	diff, notNull := entries[i].compare(entries[j], self.columns)
 	self.ctx.checkNullCompare(notNull) // warn about any null compares if applicable
 	return diff < 0
 }
@@ -128,6 +136,10 @@ func (self fileEntry) getStringField(col Column) (string, bool) {
 						return ">=", true
 					case !side && match:
 						return "<=", true
+					case side && !match && self.isMoved():
+						return ">~", true
+					case !side && !match && self.isMoved():
+						return "<~", true
 					case side && !match:
 						return ">!", true
 					case !side && !match:
@@ -155,6 +167,12 @@ func (self fileEntry) getStringField(col Column) (string, bool) {
 	}
 }
 
+// Return true if move detection paired this entry with one on the other side.
+func (self fileEntry) isMoved() bool {
+	other, _ := self.getStringField(ColOtherPath)
+	return other != ""
+}
+
 // Set a numeric field from a boolean. true -> 1, false -> 0.
 func (self fileEntry) setBoolField(col Column, value bool) {
 	if value {
@@ -220,6 +238,19 @@ func (self fileEntry) getNumericField(col Column) (int64, bool) {
 	}
 }
 
+// Get a field of either type, deriving it if necessary as with
+// getStringField and getNumericField. If no value found, return (nil, false).
+func (self fileEntry) getField(col Column) (interface{}, bool) {
+	if col.isNumeric() {
+		if n, ok := self.getNumericField(col); ok {
+			return n, true
+		}
+	} else if s, ok := self.getStringField(col); ok {
+		return s, true
+	}
+	return nil, false
+}
+
 // Get a numeric field; if it doesn't exist, return zero.
 func (self fileEntry) getNumericFieldOrZero(col Column) int64 {
 	n, _ := self.getNumericField(col)
@@ -23,6 +23,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // The possible filter operations
@@ -35,19 +37,25 @@ const (
 	opAnd           // logical AND of child filters
 	opOr            // logical OR of child filters
 	opIsNull        // field is NULL
+	opIn            // value is in a set loaded from a list or file
 )
 
 type filtOp int
 
 // Filter holds the definition of a prefilter or postfilter.
 type Filter struct {
-	op     filtOp         // the operation this filter performs
-	value  interface{}    // the value this filter uses for comparisons, if any
-	column Column         // the column this filter operates on
-	not    bool           // true to invert results
-	left   *Filter        // left child filter
-	right  *Filter        // right child filter
-	regex  *regexp.Regexp // for glob or regex filters, the pattern
+	op      filtOp          // the operation this filter performs
+	value   interface{}     // the value this filter uses for comparisons, if any
+	column  Column          // the column this filter operates on
+	not     bool            // true to invert results
+	left    *Filter         // left child filter
+	right   *Filter         // right child filter
+	regex   *regexp.Regexp  // for glob or regex filters, the pattern
+	relTime bool            // true if value is a time.Duration relative to the run start time
+	set     map[string]bool // for "in" filters, the set of values to match
+	isRef   bool            // true to compare with the value of another column
+	refCol  Column          // if isRef, the other column
+	fold    bool            // true to ignore case and Unicode normalization
 }
 
 // Pattern to parse glob expressions for conversion to regular expressions.
@@ -90,8 +98,11 @@ func GlobToRegex(pat string) (*regexp.Regexp, error) {
 	return regexp.Compile("^" + strings.Join(parts, "") + "$")
 }
 
+// Pattern matching any of the filter comparison operators
+const filterOpPat = `!?in\s+|!?\^?~=|!?\^?\*=|>=|<=|>|<|!?\^?=|!?\.isnull`
+
 // Pattern to parse filter arguments (<fieldname> <op> <value>)
-var filterArgPat = regexp.MustCompile(`\s*(\w+)\s*(!?~=|!?\*=|>=|<=|>|<|!?=|!?\.isnull)(.*)`)
+var filterArgPat = regexp.MustCompile(`\s*(\w+)\s*(` + filterOpPat + `)(.*)`)
 
 // ParseFilter parses a filter specification from a command line argument and
 // returns a new filter object implementing it, or an error if it couldn't be
@@ -111,10 +122,12 @@ func ParseFilter(arg string) (*Filter, error) {
 		return nil, fmt.Errorf("Bad filter argument: '%s'", arg)
 	}
 
-	colName := parts[1]
-	opName := parts[2]
-	data := parts[3]
+	return newCompareFilter(parts[1], parts[2], parts[3])
+}
 
+// Create a filter that compares the named column with a value using the
+// named operator.
+func newCompareFilter(colName, opName, data string) (*Filter, error) {
 	col, ok := colIndex[colName]
 	if !ok {
 		return nil, fmt.Errorf("Bad column name in filter: '%s'", colName)
@@ -123,13 +136,24 @@ func ParseFilter(arg string) (*Filter, error) {
 	var op filtOp
 	var regex *regexp.Regexp
 	var err error
+	opName = strings.TrimSpace(opName)
 	not := strings.HasPrefix(opName, "!") // invert sense of filter
 
+	// a '^' makes the =, *= and ~= operators ignore case and normalization
+	fold := strings.Contains(opName, "^")
+	if fold {
+		opName = strings.Replace(opName, "^", "", 1)
+		data = norm.NFC.String(data)
+	}
+
 	// take action based on filter op
 	switch opName {
 	case "!~=", "~=":
 		// a regular expression filter
 		op = opRegex
+		if fold {
+			data = "(?i)" + data
+		}
 		regex, err = regexp.Compile(data)
 		if err != nil {
 			return nil, err
@@ -141,6 +165,9 @@ func ParseFilter(arg string) (*Filter, error) {
 		if err != nil {
 			return nil, err
 		}
+		if fold {
+			regex, err = regexp.Compile("(?i)" + regex.String())
+		}
 	case "!=", "=":
 		op = opEq
 	case "<":
@@ -155,37 +182,66 @@ func ParseFilter(arg string) (*Filter, error) {
 		not = true
 	case "!.isnull", ".isnull":
 		op = opIsNull
+	case "!in", "in":
+		op = opIn
+		data = strings.TrimSpace(data)
 	default:
 		panic("Unexpected filter op") // shouldn't be possible due to regex match
 	}
 
 	var value interface{} = data
-	if col.isNumeric() {
-		// if numeric comparison, convert the value to a number
-		switch op {
-		case opEq, opLess, opLessEq:
-			ival, err := strconv.ParseInt(data, 10, 64)
-			if err != nil {
+	relTime := false
+	refCol, isRef := Column(0), false
+	switch op {
+	case opEq, opLess, opLessEq:
+		if strings.HasPrefix(data, `\$`) {
+			// an escaped "$" starts a literal value
+			data = data[1:]
+		} else if strings.HasPrefix(data, "$") {
+			// a value naming another column compares with that column
+			if refCol, isRef = colIndex[data[1:]]; isRef {
+				break
+			}
+		}
+		// convert numbers, sizes and dates to values comparable with the column
+		value, relTime, err = parseFilterValue(col, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// "in" sets given directly as a comma-separated list are built now; sets
+	// from files are loaded when the run starts
+	var set map[string]bool
+	if op == opIn && !strings.HasPrefix(data, "@") {
+		set = map[string]bool{}
+		for _, item := range strings.Split(data, ",") {
+			if err = addSetValue(set, col, item); err != nil {
 				return nil, err
 			}
-			value = ival
 		}
 	}
 
 	// create the filter object and return it
 	filt := Filter{
-		op:     op,
-		value:  value,
-		column: col,
-		not:    not,
-		regex:  regex,
+		op:      op,
+		value:   value,
+		column:  col,
+		not:     not,
+		relTime: relTime,
+		set:     set,
+		isRef:   isRef,
+		refCol:  refCol,
+		fold:    fold,
+		regex:   regex,
 	}
 	return &filt, nil
 }
 
 // Given a list of filter arguments, "compile" them into a single filter tree.
 // Any AND or OR filters are used as-is. AND/OR filters must appear in prefix
-// order before their operands (forward Polish notation).  Any remaining
+// order before their operands (forward Polish notation), except for those
+// that already have children, such as from a filter expression. Any remaining
 // filters in the list which are not children of AND/OR filters get joined
 // together with new auto-generated AND filters (they are implicitly ANDed).
 func compileFilter(args []*Filter) (*Filter, error) {
@@ -198,6 +254,10 @@ func compileFilter(args []*Filter) (*Filter, error) {
 		filt := args[i]
 		switch filt.op {
 		case opAnd, opOr:
+			if filt.left != nil {
+				// already compiled from an expression
+				break
+			}
 			// found one; pull the following two list items as its children
 			if i >= len(args)-2 {
 				return nil, fmt.Errorf("Filter expression AND/OR op: not enough arguments provided")
@@ -229,20 +289,28 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 		return true, true
 	}
 
-	// AND/OR filters evaluate children
+	// AND/OR filters evaluate children; the right child is only needed if the
+	// left one doesn't decide the result
 	switch self.op {
-	case opAnd:
+	case opAnd, opOr:
 		match, ok := self.left.filter(entry)
-		if !match || !ok {
-			return match && ok, ok
+		if ok && match == (self.op == opAnd) {
+			match, ok = self.right.filter(entry)
 		}
-		return self.right.filter(entry)
-	case opOr:
-		match, ok := self.left.filter(entry)
-		if match || !ok {
-			return match && ok, ok
+		match = match && ok
+		if self.not && ok {
+			match = !match
+		}
+		return match, ok
+	}
+
+	// get the value to compare against, either the literal or another column
+	value := self.value
+	if self.isRef {
+		var ok bool
+		if value, ok = entry.getField(self.refCol); !ok {
+			return false, false
 		}
-		return self.right.filter(entry)
 	}
 
 	diff := 0  // result of comparison +/0/-
@@ -255,7 +323,7 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 		} else if !ok {
 			return false, false
 		}
-		if fival, ok := self.value.(int64); ok {
+		if fival, ok := value.(int64); ok {
 			// filter value is numeric; calculate diff
 			switch {
 			case fival > eival:
@@ -267,6 +335,10 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 			// filter value is not numeric; may be regex, for example
 			// convert entry value to string
 			sval = strconv.FormatInt(eival, 10)
+			if s, ok := value.(string); ok && self.isRef {
+				// compared to a string column
+				diff = strings.Compare(s, sval)
+			}
 		}
 	} else {
 		// do string evaluation
@@ -277,7 +349,15 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 		} else if !ok {
 			return false, false
 		}
-		diff = strings.Compare(self.value.(string), sval)
+		if ival, ok := value.(int64); ok {
+			// compared to a numeric column
+			value = strconv.FormatInt(ival, 10)
+		}
+		if self.fold {
+			sval = foldString(sval)
+			value = foldString(value.(string))
+		}
+		diff = strings.Compare(value.(string), sval)
 	}
 
 	var match bool
@@ -291,6 +371,8 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 		match = diff > 0
 	case opRegex, opGlob:
 		match = self.regex.MatchString(sval)
+	case opIn:
+		match = self.set[sval]
 	default:
 		panic("Bad filter operation code")
 	}
@@ -299,3 +381,20 @@ func (self *Filter) filter(entry fileEntry) (bool, bool) {
 	}
 	return match, true
 }
+
+// Add the columns used by this filter and any child filters to a set.
+func (self *Filter) addColumns(cols map[Column]bool) {
+	if self == nil {
+		return
+	}
+	switch self.op {
+	case opAnd, opOr:
+		self.left.addColumns(cols)
+		self.right.addColumns(cols)
+	default:
+		cols[self.column] = true
+		if self.isRef {
+			cols[self.refCol] = true
+		}
+	}
+}
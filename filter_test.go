@@ -198,3 +198,69 @@ func Test_Filter_filter(t *testing.T) {
 		checkValErr1(t, test.want, got, "", nil)
 	}
 }
+
+func Test_Filter_filterColumnRef(t *testing.T) {
+	var tests = []struct {
+		arg   string
+		entry fileEntry
+		want  [2]bool
+	}{
+		{"uid=$gid", fileEntry{ColUid: int64(5), ColGid: int64(5)}, [2]bool{true, true}},
+		{"uid!=$gid", fileEntry{ColUid: int64(5), ColGid: int64(5)}, [2]bool{false, true}},
+		{"uid!=$gid", fileEntry{ColUid: int64(5), ColGid: int64(7)}, [2]bool{true, true}},
+		{"uid<$gid", fileEntry{ColUid: int64(5), ColGid: int64(7)}, [2]bool{true, true}},
+		{"uid>=$gid", fileEntry{ColUid: int64(5), ColGid: int64(7)}, [2]bool{false, true}},
+		{"user<$group", fileEntry{ColUser: "adm", ColGroup: "bob"}, [2]bool{true, true}},
+		{"user<$group", fileEntry{ColUser: "adm"}, [2]bool{false, false}},
+		{"uid=$gid", fileEntry{ColGid: int64(5)}, [2]bool{false, false}},
+		{"user=$group", fileEntry{ColUser: "bob", ColGroup: "bob"}, [2]bool{true, true}},
+		{"size=$uid", fileEntry{ColSize: int64(10), ColUid: int64(10)}, [2]bool{true, true}},
+		{"uid=$user", fileEntry{ColUid: int64(10), ColUser: "10"}, [2]bool{true, true}},
+		{"user=$uid", fileEntry{ColUid: int64(10), ColUser: "bob"}, [2]bool{false, true}},
+		{"base=$path", fileEntry{ColPath: "b.txt"}, [2]bool{true, true}},     // derived column
+		{"path=$nosuch", fileEntry{ColPath: "$nosuch"}, [2]bool{true, true}}, // not a column
+		{`base=\$size`, fileEntry{ColBase: "$size"}, [2]bool{true, true}},    // escaped
+		{`base=\$size`, fileEntry{ColBase: "a", ColSize: int64(1)}, [2]bool{false, true}},
+		{`base<\$x`, fileEntry{ColBase: "$a"}, [2]bool{true, true}},
+		{"path~=$gid", fileEntry{ColPath: "a"}, [2]bool{false, true}},        // only compares
+	}
+	for _, test := range tests {
+		filt, err := ParseFilter(test.arg)
+		checkValErr1(t, nil, nil, "", err)
+		var got [2]bool
+		got[0], got[1] = filt.filter(test.entry)
+		checkValErr1(t, test.want, got, "", nil)
+	}
+
+	filt, _ := ParseFilterExpr("user < $group or uid != $gid")
+	cols := map[Column]bool{}
+	filt.addColumns(cols)
+	checkVal(t, map[Column]bool{ColUser: true, ColGroup: true, ColUid: true, ColGid: true}, cols)
+}
+
+func Test_Filter_filterFold(t *testing.T) {
+	var tests = []struct {
+		arg   string
+		entry fileEntry
+		want  [2]bool
+	}{
+		{"path^=a/README.txt", fileEntry{ColPath: "a/ReadMe.TXT"}, [2]bool{true, true}},
+		{"path=a/README.txt", fileEntry{ColPath: "a/ReadMe.TXT"}, [2]bool{false, true}},
+		{"path!^=a/README.txt", fileEntry{ColPath: "a/ReadMe.TXT"}, [2]bool{false, true}},
+		{"base^=caf\u00e9", fileEntry{ColPath: "x/Cafe\u0301"}, [2]bool{true, true}},
+		{"base^*=*.TXT", fileEntry{ColPath: "x/a.txt"}, [2]bool{true, true}},
+		{"base*=*.TXT", fileEntry{ColPath: "x/a.txt"}, [2]bool{false, true}},
+		{"base!^*=*.TXT", fileEntry{ColPath: "x/a.txt"}, [2]bool{false, true}},
+		{"path^~=^CAFÉ", fileEntry{ColPath: "café/x"}, [2]bool{true, true}},
+		{"path~=^CAF", fileEntry{ColPath: "cafe/x"}, [2]bool{false, true}},
+		{"user^=$group", fileEntry{ColUser: "Bob", ColGroup: "bob"}, [2]bool{true, true}},
+		{"path^=a", fileEntry{}, [2]bool{false, false}},
+	}
+	for _, test := range tests {
+		filt, err := ParseFilter(test.arg)
+		checkValErr1(t, nil, nil, "", err)
+		var got [2]bool
+		got[0], got[1] = filt.filter(test.entry)
+		checkValErr1(t, test.want, got, "", nil)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold a string for case-insensitive comparison. The string is also
+// converted to Unicode normalization form C, so that a name in decomposed
+// form (as written by macOS, for example) equals its precomposed form.
+func foldString(s string) string {
+	return norm.NFC.String(strings.ToLower(norm.NFC.String(s)))
+}
+
+// Fold the file name fields of a key entry in place. Fields derived from
+// the path are derived from the folded path.
+func foldNameFields(key fileEntry) {
+	for col, val := range key {
+		if s, ok := val.(string); ok && isNameColumn(col) {
+			key[col] = foldString(s)
+		}
+	}
+}
+
+// Return true if the column holds a file name or part of one; these are the
+// key columns folded by the FoldKeys option.
+func isNameColumn(col Column) bool {
+	switch col {
+	case ColPath, ColBase, ColExt, ColDir:
+		return true
+	}
+	return false
+}
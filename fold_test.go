@@ -0,0 +1,56 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_foldString(t *testing.T) {
+	var tests = []struct {
+		arg  string
+		want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"ReadMe.TXT", "readme.txt"},
+		{"Cafe\u0301", "caf\u00e9"}, // decomposed
+		{"CAF\u00c9", "caf\u00e9"},  // precomposed upper case
+		{"A\u030a/x", "\u00e5/x"},   // A with ring above
+		{"Ångström", "ångström"},
+	}
+	for _, test := range tests {
+		checkVal(t, test.want, foldString(test.arg))
+	}
+}
+
+func Test_foldNameFields(t *testing.T) {
+	key := fileEntry{ColPath: "Cafe\u0301/A.TXT", ColExt: ".TXT", ColUser: "Bob", ColSize: int64(1)}
+	foldNameFields(key)
+	checkVal(t, fileEntry{ColPath: "caf\u00e9/a.txt", ColExt: ".txt", ColUser: "Bob", ColSize: int64(1)}, key)
+	base, _ := key.getStringField(ColBase)
+	checkVal(t, "a.txt", base)
+}
+
+func Test_isNameColumn(t *testing.T) {
+	checkVal(t, true, isNameColumn(ColPath))
+	checkVal(t, true, isNameColumn(ColExt))
+	checkVal(t, false, isNameColumn(ColUser))
+	checkVal(t, false, isNameColumn(ColSize))
+}
@@ -62,6 +62,44 @@ func filterOption(filts *[]*sifter.Filter) func(string) error {
 	}
 }
 
+// Factory function to create an option handler that parses infix filter
+// expressions. Handler operates on the referenced filter list.
+func whereOption(filts *[]*sifter.Filter) func(string) error {
+	return func(val string) error {
+		filt, err := sifter.ParseFilterExpr(val)
+		*filts = append(*filts, filt)
+		return err
+	}
+}
+
+// Factory function to create an option handler that parses path rewrite
+// rules. Handler operates on the referenced rule list.
+func pathMapOption(maps *[]sifter.PathMap) func(string) error {
+	return func(val string) error {
+		m, err := sifter.ParsePathMap(val)
+		*maps = append(*maps, m)
+		return err
+	}
+}
+
+// Handler for --map-id option adds to the owner id map for a column.
+func idMapAction(arg string) error {
+	col, idMap, err := sifter.ParseIdMap(arg)
+	if err != nil {
+		return err
+	}
+	if ctx.IdMaps == nil {
+		ctx.IdMaps = map[sifter.Column]sifter.IdMap{}
+	}
+	if ctx.IdMaps[col] == nil {
+		ctx.IdMaps[col] = sifter.IdMap{}
+	}
+	for from, to := range idMap {
+		ctx.IdMaps[col][from] = to
+	}
+	return nil
+}
+
 // Handler for --exclude option adds an exclude pattern.
 func excludeAction(arg string) error {
 	rex, err := sifter.GlobToRegex(arg)
@@ -71,6 +109,43 @@ func excludeAction(arg string) error {
 	return err
 }
 
+// Handler for --grep option sets the content search pattern.
+func grepAction(arg string) (err error) {
+	ctx.Grep, err = regexp.Compile(arg)
+	return
+}
+
+// Handler for --grep-max option sets the max bytes to search in each file.
+func grepMaxAction(arg string) (err error) {
+	ctx.GrepMaxBytes, err = strconv.ParseInt(arg, 10, 64)
+	return
+}
+
+// Handler for --entropy-sample option sets the number of bytes to use for
+// the entropy column.
+func entropySampleAction(arg string) (err error) {
+	ctx.EntropySample, err = strconv.ParseInt(arg, 10, 64)
+	return
+}
+
+// Handler for --mtime-tolerance option accepts a number of seconds or a
+// duration like "2s".
+func mtimeToleranceAction(arg string) error {
+	if secs, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		ctx.MtimeTolerance = secs
+		return nil
+	}
+	d, err := time.ParseDuration(arg)
+	ctx.MtimeTolerance = int64(d / time.Second)
+	return err
+}
+
+// Handler for --mtime-hours option sets the max hour offset for mtimes.
+func mtimeHoursAction(arg string) (err error) {
+	ctx.MtimeHours, err = strconv.ParseInt(arg, 10, 64)
+	return
+}
+
 // Show version info.
 func showVersionAndExit() {
 	fmt.Println()
@@ -110,11 +185,39 @@ func outZoneAction(arg string) (err error) {
 	return
 }
 
-// Nonoption argument handler adds root to current side; ":" switches from
-// left to right side.
+// Option handler for --aggregate adds aggregate values for each group
+func aggregateAction(arg string) error {
+	aggs, err := sifter.ParseAggregates(arg)
+	ctx.Aggregates = append(ctx.Aggregates, aggs...)
+	return err
+}
+
+// Option handler for --histogram sets the buckets to count output files in
+func histogramAction(arg string) (err error) {
+	ctx.Histogram, err = sifter.ParseHistogram(arg)
+	return
+}
+
+// Option handler for --histogram-bars sets the width of the histogram bars
+func histogramBarsAction(arg string) (err error) {
+	ctx.HistogramBars, err = strconv.Atoi(arg)
+	return
+}
+
+// Option handler for --limit and --top sets the max entries to output
+func limitAction(arg string) (err error) {
+	ctx.Limit, err = strconv.Atoi(arg)
+	if err == nil && ctx.Limit <= 0 {
+		err = fmt.Errorf("--limit must be at least 1: %s", arg)
+	}
+	return
+}
+
+// Nonoption argument handler adds root to current side; each ":" switches
+// to the next side.
 func argAction(arg string) error {
 	if arg == ":" {
-		ctx.CurSide = true
+		ctx.CurSide++
 	} else {
 		ctx.Roots[ctx.CurSide] = append(ctx.Roots[ctx.CurSide], arg)
 	}
@@ -137,13 +240,42 @@ func parseArgs(args []string) error {
 		Option("c columns     ", columnOption(&ctx.OutCols), "=COLUMNS; Output columns (default: ostp)").
 		Option("s sort        ", columnOption(&ctx.SortCols), "=COLUMNS; Sort output using these fields (default: no sort)").
 		Option("k key         ", columnOption(&ctx.KeyCols), "=COLUMNS;Set fields used in comparisons  (default: psto)").
+		Option("i identity    ", columnOption(&ctx.IdentityCols), "=COLUMNS; Pair entries on each side by these fields to classify changes (enables change column)").
+		Option("  side-by-side", columnOption(&ctx.SideCols), "=COLUMNS; Output each pair of entries on one line with these fields for each side, marking differences with '*'").
+		Option("  group-by    ", columnOption(&ctx.GroupCols), "=COLUMNS; Output a line for each group of entries with the same values of these fields").
+		Option("  aggregate   ", aggregateAction, "=AGGS; Values to output for each group, like 'count,sum:size,max:mtime' (default: count,sum:size)").
+		Option("  group-sort  ", &ctx.GroupSort, "=KEYS; Sort groups by these group fields or aggregates, '/' to reverse (default: group fields)").
+		Option("  verify-content", &ctx.VerifyContent, "Compare files with the same key byte for byte, splitting those that differ; report hash collisions").
+		Option("  duplicates  ", &ctx.Duplicates, "Output groups of files with the same compare key as blocks, by reclaimable space (default key: size,md5)").
+		Option("  fdupes      ", &ctx.Fdupes, "Output duplicate groups as lists of paths like fdupes (implies --duplicates, --plain)").
+		Option("  dedupe      ", &ctx.Dedupe, "=hardlink|reflink; Replace duplicates with links to one copy, after comparing content (implies --duplicates)").
+		Option("  keep        ", &ctx.KeepRule, "=RULE; Copy to keep for --dedupe: first, oldest, newest or shortest (default: first)").
+		Option("  dry-run     ", &ctx.DryRun, "Only log what --dedupe would change").
+		Option("  ignore-perms", &ctx.IgnorePerms, "Hard link copies even if their owner, group or mode differ from the kept file").
+		Option("  dedupe-log  ", &ctx.DedupeLog, "=PATH; Write the --dedupe log to a file instead of the duplicate groups").
+		Option("  sync-plan   ", &ctx.SyncPlan, "=FORMAT; Output a plan to make the right side like the left as a 'sh' script, 'rsync' --files-from list or 'json'").
+		Option("  limit       ", limitAction, "=N; Only output the first N entries in sort order (default: no limit)").
+		Option("  top         ", limitAction, "=N; Same as --limit").
+		Option("  limit-by    ", columnOption(&ctx.LimitCols), "=COLUMNS; Apply --limit to each group of entries with the same values of these fields").
 		Option("5 md5         ", &ctx.AddMd5, "Add md5 column to compare key and output").
 		Option("2 sha256      ", &ctx.AddSha256, "Add sha256 column to compare key and output").
 		Option("A sha512      ", &ctx.AddSha512, "Add sha512 column to compare key and output").
 		Option("1 sha1        ", &ctx.AddSha1, "Add sha1 column to compare key and output").
+		Option("  merge       ", &ctx.Merge, "Classify paths on three sides 'base : B : C' for a three-way merge (enables merge column)").
+		Option("  merge-actions", &ctx.MergeActions, "=PATH; Write the three-way merge action list to a file (implies --merge)").
+		Option("  fold-keys   ", &ctx.FoldKeys, "Compare path, base, ext and dir keys ignoring case and Unicode normalization").
+		Option("  moves       ", &ctx.DetectMoves, "Pair unmatched files with the same content on each side as moved (membership <~ and >~)").
+		Option("  map-left    ", pathMapOption(&ctx.LeftPathMaps), "=REGEX=>REPL; Rewrite left side paths matching REGEX before comparing").
+		Option("  map-right   ", pathMapOption(&ctx.RightPathMaps), "=REGEX=>REPL; Rewrite right side paths matching REGEX before comparing").
+		Option("  map-id      ", idMapAction, "=COL:LEFT=RIGHT,...; Compare left side uid, gid, user or group LEFT as RIGHT (or COL:@FILE)").
+		Option("  mtime-tolerance", mtimeToleranceAction, "=DURATION; Key mtimes within DURATION (e.g. '2s') of each other match").
+		Option("  mtime-hours ", mtimeHoursAction, "=N; Key mtimes offset by up to N whole hours (e.g. for DST) match").
+		Option("  entropy-sample", entropySampleAction, "=BYTES; Only compute entropy column over the first BYTES of each file").
 		Section("Pre-analysis filtering:").
 		Option("e prefilter   ", filterOption(&ctx.PreFilterArgs), "=FILTER-EXP; Filter files before indexing").
 		Option("P prunefilter ", filterOption(&ctx.PruneFilterArgs), "=FILTER-EXP; Filter directories before descending").
+		Option("  pre-where   ", whereOption(&ctx.PreFilterArgs), "=EXPR; Like --prefilter, but with an infix expression like 'a=1 and (b<2 or not c=3)'").
+		Option("  prune-where ", whereOption(&ctx.PruneFilterArgs), "=EXPR; Like --prunefilter, but with an infix expression").
 		Option("b base-match  ", baseAction, "=GLOB-PAT; Shortcut for --prefilter 'base*=*GLOB-PAT*'").
 		Option("x exclude     ", excludeAction, "=GLOB-PAT; Exclude file system files and/or dir trees by path glob").
 		Option("R regular-only", &ctx.RegularOnly, "Only consider regular files while scanning file system").
@@ -151,13 +283,19 @@ func parseArgs(args []string) error {
 		Option("X xdev        ", &ctx.XDev, "Don't descend directories on different file systems").
 		Section("Post-analysis filtering:").
 		Option("f postfilter  ", filterOption(&ctx.PostFilterArgs), "=FILTER-EXP; Filter output after analysis").
-		Option("m membership  ", &ctx.MembershipFilt, "=CHARS; Filter output by membership (one or more of lrLR)").
+		Option("w where       ", whereOption(&ctx.PostFilterArgs), "=EXPR; Like --postfilter, but with an infix expression like 'a=1 and (b<2 or not c=3)'").
+		Option("m membership  ", &ctx.MembershipFilt, "=CHARS; Filter output by membership (one or more of lrLRon)").
 		Option("d diff        ", func() { ctx.MembershipFilt = "LR" }, "Show differing entries only; shortcut for -mLR").
+		Option("g grep        ", grepAction, "=REGEX; Only output files with lines matching REGEX; enables grepcount, grepline columns").
+		Option("  grep-max    ", grepMaxAction, "=BYTES; Only search the first BYTES bytes of each file for --grep").
+		Option("  grep-binary ", &ctx.GrepBinary, "Also search files that look like binary data for --grep").
 		Option("  nodetect    ", &ctx.NoDetect, "Don't try to detect type of regular files specified as roots").
 		Section("Output formatting").
 		Option("o out         ", &ctx.OutputPath, "=PATH; Output to file instead of stdout").
 		Option("Y verify      ", &ctx.Verify, "Checks that all left entries are matched on right (Analogous to 'md5sum -c'.)").
 		Option("S summary     ", &ctx.SummaryOnly, "Only output summary info; no entry lines").
+		Option("  histogram   ", histogramAction, "=BUCKETS; Show count and size of output files by 'size', 'size:1M,1G,...' or 'age:day|week|month|year' in summary").
+		Option("  histogram-bars", histogramBarsAction, "=WIDTH; Show a bar up to WIDTH chars long for the size of each histogram bucket").
 		Option("p plain       ", &ctx.Plain, "Only output entries, no header info").
 		Option("0 plain0      ", &ctx.Plain0, "Like 'plain', but also separate all output fields with null chars").
 		Option("G group-nums  ", &ctx.GroupNumerics, "Output ',' between groups of numeric digits").
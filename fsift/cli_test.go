@@ -33,6 +33,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -366,6 +367,32 @@ var group2 = `| File Sifter output file - V1 |
 |    Indexed:      8     9
 |     Output:      8     9`
 
+var syncplan1 = `#!/bin/sh
+# Sync plan from File Sifter: make DST like SRC
+set -e
+SRC='$T/1/x'
+DST='$T/1/y'
+cp -p "$SRC"/'a' "$DST"/'a'
+cp -p "$SRC"/'c' "$DST"/'c'
+rm -f "$DST"/'b'
+rmdir "$DST"/'d'`
+
+var dedupe1 = `| File Sifter output file - V1 |
+| Compare keys: size,md5
+| Evaluated columns: path,size,mtime,modestr,md5,abspath
+| Columns: modestr,size,mtime,path
+| Duplicate group 1: 2 copies of 3 bytes, 3 bytes reclaimable
+| would-hardlink $T/1/x/c $T/1/y/c
+  -rw-rw-r--  3  2016-11-24T15:06:43Z  x/c
+  -rw-rw-r--  3  2016-11-24T15:06:46Z  y/c
+|   STATISTICS:  Count  Size
+|      Scanned:      8     9
+|      Indexed:      8     9
+|       Output:      2     6
+|   Duplicates:      2     6
+|  Reclaimable:      1     3
+| Would dedupe:      1     3`
+
 // Defines a test case
 type test struct {
 	name     string   // name for diag output
@@ -472,6 +499,14 @@ var tests = []test{
 		// check that null compares with --ignore-nulls don't create an error
 		"nulls2", []string{"$F", ":", "$F", "-N", "-kp5"}, false, "", 0,
 	},
+	{
+		// check a shell script sync plan
+		"syncplan1", []string{"$T/1/x", ":", "$T/1/y", "--sync-plan", "sh"}, false, syncplan1, 0,
+	},
+	{
+		// check that a dedupe dry run only logs what it would do
+		"dedupe1", []string{"$T/1", "--dedupe", "hardlink", "--dry-run"}, false, dedupe1, 0,
+	},
 }
 
 // An object to help verify that the run output matches expectations
@@ -657,7 +692,8 @@ func Test_main(t *testing.T) {
 		// fmt.Println("@@@\n", out)
 		// fmt.Println("!!!\n", eOut)
 		if test.wantOut != "" {
-			got := newAnalyzer(t, &output)
+			// substitute the temp dir path in the output, like in the args
+			got := newAnalyzer(t, strings.NewReader(strings.Replace(out, dirPath, "$T", -1)))
 			want := newAnalyzer(t, strings.NewReader(strings.Trim(test.wantOut, "\n")))
 			if test.postSort {
 				sort.Strings(got.files)
@@ -667,3 +703,72 @@ func Test_main(t *testing.T) {
 		}
 	}
 }
+
+// Command lines that are rejected with a fatal error, with the expected
+// error message
+var fatalTests = []struct {
+	name    string
+	args    []string
+	wantErr string
+}{
+	{
+		// a limit would cut the sync plan
+		"limit1", []string{"$T/x", ":", "$T/y", "--sync-plan", "sh", "--limit", "1"},
+		"--limit can't be used with --sync-plan, --duplicates or --dedupe",
+	},
+	{
+		// a limit would cut the duplicate groups
+		"limit2", []string{"$T/x", "--dedupe", "hardlink", "--top", "1"},
+		"--limit can't be used with --sync-plan, --duplicates or --dedupe",
+	},
+	{
+		// the files of an FSIFT file can't be searched
+		"grep1", []string{"$T/saved.fsift", "--grep", "foo"},
+		"--grep can't search the files of a FSIFT file root",
+	},
+	{
+		// changes need two sides to pair
+		"sides1", []string{"$T/x", "--identity", "path"},
+		"Move detection, change classification and side-by-side output only work with two sides",
+	},
+	{
+		"sides2", []string{"$T/x", "-c", "change,path"},
+		"Move detection, change classification and side-by-side output only work with two sides",
+	},
+}
+
+// Run the fatal error tests. A fatal error exits the program, so each test
+// runs the test binary again in a child process, which runs the command
+// line given in the environment, one argument per line.
+func Test_fatal(t *testing.T) {
+	if args := os.Getenv("FSIFT_TEST_ARGS"); args != "" {
+		ctx = sifter.NewContext()
+		os.Exit(run(strings.Split(args, "\n")))
+	}
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test", err)
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	os.Mkdir(filepath.Join(dirPath, "x"), 0755)
+	os.Mkdir(filepath.Join(dirPath, "y"), 0755)
+	ioutil.WriteFile(filepath.Join(dirPath, "saved.fsift"), []byte(fsfile1), 0644)
+
+	for _, test := range fatalTests {
+		fmt.Println("Running fatal test case ", test.name)
+		args := []string{}
+		for _, arg := range test.args {
+			args = append(args, strings.Replace(arg, "$T", dirPath, -1))
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^Test_fatal$")
+		cmd.Env = append(os.Environ(), "FSIFT_TEST_ARGS="+strings.Join(args, "\n"))
+		out, err := cmd.CombinedOutput()
+		exitErr, ok := err.(*exec.ExitError)
+		checkVal(t, true, ok)
+		if ok {
+			checkVal(t, 2, exitErr.ExitCode())
+		}
+		checkVal(t, true, strings.Contains(string(out), test.wantErr))
+	}
+}
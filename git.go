@@ -0,0 +1,671 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Git object types, as encoded in pack files
+const (
+	gitCommit   = 1
+	gitTree     = 2
+	gitBlob     = 3
+	gitTag      = 4
+	gitOfsDelta = 6
+	gitRefDelta = 7
+)
+
+// Names of git object types, as used in loose object headers
+var gitTypeNames = map[string]int{
+	"commit": gitCommit,
+	"tree":   gitTree,
+	"blob":   gitBlob,
+	"tag":    gitTag,
+}
+
+// Matches a full hex object ID
+var gitShaPat = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Max number of symbolic refs to follow when resolving a ref
+const maxGitRefDepth = 10
+
+// A git repository opened for reading objects
+type gitRepo struct {
+	dir   string            // the git directory (the one containing 'objects')
+	packs []*gitPack        // the pack files in the repository
+	refs  map[string]string // refs from the packed-refs file
+}
+
+// An opened git pack file and its index
+type gitPack struct {
+	file    *os.File // the open .pack file
+	shas    []byte   // sorted binary object IDs from the index, 20 bytes each
+	offsets []int64  // pack file offsets for each object ID
+}
+
+// Split a root argument of the form "REPO@REF" into the repository directory
+// and the ref. Returns false if the argument doesn't have that form or if the
+// directory doesn't look like a git repository.
+func splitGitRoot(arg string) (string, string, bool) {
+	i := strings.LastIndex(arg, "@")
+	if i < 0 {
+		return "", "", false
+	}
+	repoDir, ref := arg[:i], arg[i+1:]
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if findGitDir(repoDir) == "" {
+		return "", "", false
+	}
+	return repoDir, ref, true
+}
+
+// Return the git directory for a repository: either the directory itself for
+// a bare repository, or its '.git' subdirectory. Returns an empty string if
+// it doesn't look like a git repository.
+func findGitDir(repoDir string) string {
+	for _, dir := range []string{filepath.Join(repoDir, ".git"), repoDir} {
+		fi, err := os.Stat(filepath.Join(dir, "objects"))
+		if err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// Open a git repository and the indexes of all of its pack files.
+func openGitRepo(repoDir string) (*gitRepo, error) {
+	dir := findGitDir(repoDir)
+	if dir == "" {
+		return nil, fmt.Errorf("Not a git repository: %s", repoDir)
+	}
+	repo := gitRepo{dir: dir, refs: map[string]string{}}
+	idxPaths, _ := filepath.Glob(filepath.Join(dir, "objects", "pack", "*.idx"))
+	for _, idxPath := range idxPaths {
+		pack, err := openGitPack(idxPath)
+		if err != nil {
+			repo.close()
+			return nil, err
+		}
+		repo.packs = append(repo.packs, pack)
+	}
+	// packed-refs lines are "<sha> <refname>"; peeled tag lines start with '^'
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "packed-refs")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && gitShaPat.MatchString(fields[0]) {
+				repo.refs[fields[1]] = fields[0]
+			}
+		}
+	}
+	return &repo, nil
+}
+
+// Close the pack files of a repository.
+func (self *gitRepo) close() {
+	for _, pack := range self.packs {
+		pack.file.Close()
+	}
+}
+
+// Open a pack file given the path of its version 2 index file.
+func openGitPack(idxPath string) (*gitPack, error) {
+	idx, err := ioutil.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || string(idx[:4]) != "\xfftOc" || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("Unsupported git pack index format: %s", idxPath)
+	}
+	// the last fanout entry is the total object count
+	count := int(binary.BigEndian.Uint32(idx[8+255*4:]))
+	shaBase := 8 + 256*4
+	offBase := shaBase + count*20 + count*4 // skip the CRC table
+	largeBase := offBase + count*4
+	if len(idx) < largeBase {
+		return nil, fmt.Errorf("Truncated git pack index: %s", idxPath)
+	}
+	pack := gitPack{
+		shas:    idx[shaBase : shaBase+count*20],
+		offsets: make([]int64, count),
+	}
+	for i := range pack.offsets {
+		off := binary.BigEndian.Uint32(idx[offBase+i*4:])
+		if off&0x80000000 != 0 {
+			// large offsets are stored in a separate table
+			j := largeBase + int(off&0x7fffffff)*8
+			if len(idx) < j+8 {
+				return nil, fmt.Errorf("Truncated git pack index: %s", idxPath)
+			}
+			pack.offsets[i] = int64(binary.BigEndian.Uint64(idx[j:]))
+		} else {
+			pack.offsets[i] = int64(off)
+		}
+	}
+	pack.file, err = os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// Look up a binary object ID in the pack index. Returns the offset of the
+// object in the pack file, or false if it isn't in this pack.
+func (self *gitPack) find(sha []byte) (int64, bool) {
+	n := len(self.offsets)
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(self.shas[i*20:i*20+20], sha) >= 0
+	})
+	if i < n && bytes.Equal(self.shas[i*20:i*20+20], sha) {
+		return self.offsets[i], true
+	}
+	return 0, false
+}
+
+// Resolve a ref name (branch, tag, HEAD, etc.) or a full hex object ID to an
+// object ID, following the same search order as git.
+func (self *gitRepo) resolveRef(ref string, depth int) (string, error) {
+	if gitShaPat.MatchString(ref) {
+		return ref, nil
+	}
+	if depth > maxGitRefDepth {
+		return "", fmt.Errorf("Too many levels of symbolic refs: %s", ref)
+	}
+	for _, name := range []string{ref, "refs/" + ref, "refs/tags/" + ref, "refs/heads/" + ref,
+		"refs/remotes/" + ref, "refs/remotes/" + ref + "/HEAD"} {
+		data, err := ioutil.ReadFile(filepath.Join(self.dir, filepath.FromSlash(name)))
+		if err == nil {
+			text := strings.TrimSpace(string(data))
+			if strings.HasPrefix(text, "ref: ") {
+				return self.resolveRef(strings.TrimPrefix(text, "ref: "), depth+1)
+			}
+			if gitShaPat.MatchString(text) {
+				return text, nil
+			}
+		}
+		if sha, ok := self.refs[name]; ok {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("Can't resolve git ref '%s'", ref)
+}
+
+// Read the header of a loose object, returning its type and size, and a
+// reader positioned at the start of the object data.
+func readLooseHeader(r io.Reader) (int, int64, *bufio.Reader, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	br := bufio.NewReader(zr)
+	header, err := br.ReadString(0)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	parts := strings.SplitN(strings.TrimSuffix(header, "\x00"), " ", 2)
+	typ, ok := gitTypeNames[parts[0]]
+	if !ok || len(parts) != 2 {
+		return 0, 0, nil, fmt.Errorf("Bad git object header: %q", header)
+	}
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	return typ, size, br, err
+}
+
+// Read an object from the repository. If sizeOnly is set, the object data
+// isn't returned if it can be avoided. Returns the object type, size and data.
+func (self *gitRepo) readObject(sha string, sizeOnly bool) (int, int64, []byte, error) {
+	// try loose objects first
+	f, err := os.Open(filepath.Join(self.dir, "objects", sha[:2], sha[2:]))
+	if err == nil {
+		defer f.Close()
+		typ, size, r, err := readLooseHeader(f)
+		if err != nil || sizeOnly {
+			return typ, size, nil, err
+		}
+		data, err := ioutil.ReadAll(r)
+		if err == nil && int64(len(data)) != size {
+			err = fmt.Errorf("Bad size for git object %s", sha)
+		}
+		return typ, size, data, err
+	}
+	bin, err := hex.DecodeString(sha)
+	if err != nil || len(bin) != 20 {
+		return 0, 0, nil, fmt.Errorf("Bad git object ID: %s", sha)
+	}
+	for _, pack := range self.packs {
+		if offset, ok := pack.find(bin); ok {
+			return self.readPackObject(pack, offset, sizeOnly)
+		}
+	}
+	return 0, 0, nil, fmt.Errorf("Git object not found: %s", sha)
+}
+
+// Read a variable length size from the start of delta data.
+func readDeltaSize(delta []byte) (int64, []byte) {
+	size, shift := int64(0), uint(0)
+	for len(delta) > 0 {
+		b := delta[0]
+		delta = delta[1:]
+		size |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return size, delta
+}
+
+// Read an object from a pack file at the given offset, resolving any deltas.
+// If sizeOnly is set, the data of deltified objects is not reconstructed.
+func (self *gitRepo) readPackObject(pack *gitPack, offset int64, sizeOnly bool) (int, int64, []byte, error) {
+	r := bufio.NewReader(io.NewSectionReader(pack.file, offset, 1<<62))
+	// the header has the type and a variable length size
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	typ := int(b>>4) & 7
+	size, shift := int64(b&15), uint(4)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, 0, nil, err
+		}
+		size |= int64(b&0x7f) << shift
+		shift += 7
+	}
+
+	// find the base object of deltas
+	var baseOffset int64
+	var baseSha string
+	switch typ {
+	case gitOfsDelta:
+		// offset encoding adds one for each continuation byte
+		b, err = r.ReadByte()
+		rel := int64(b & 0x7f)
+		for err == nil && b&0x80 != 0 {
+			b, err = r.ReadByte()
+			rel = ((rel + 1) << 7) | int64(b&0x7f)
+		}
+		baseOffset = offset - rel
+	case gitRefDelta:
+		bin := make([]byte, 20)
+		_, err = io.ReadFull(r, bin)
+		baseSha = hex.EncodeToString(bin)
+	case gitCommit, gitTree, gitBlob, gitTag:
+		if sizeOnly {
+			return typ, size, nil, nil
+		}
+	default:
+		err = fmt.Errorf("Bad git pack object type %d", typ)
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if int64(len(data)) != size {
+		return 0, 0, nil, fmt.Errorf("Bad size for git pack object at offset %d", offset)
+	}
+	if typ != gitOfsDelta && typ != gitRefDelta {
+		return typ, size, data, nil
+	}
+
+	// read the base object and apply the delta to it
+	var base []byte
+	if typ == gitOfsDelta {
+		typ, _, base, err = self.readPackObject(pack, baseOffset, sizeOnly)
+	} else {
+		typ, _, base, err = self.readObject(baseSha, sizeOnly)
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if sizeOnly {
+		// the result size is the second number in the delta header
+		_, rest := readDeltaSize(data)
+		size, _ = readDeltaSize(rest)
+		return typ, size, nil, nil
+	}
+	data, err = applyGitDelta(base, data)
+	return typ, int64(len(data)), data, err
+}
+
+// Apply git delta instructions to a base object, returning the new object.
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	srcSize, delta := readDeltaSize(delta)
+	dstSize, delta := readDeltaSize(delta)
+	if srcSize != int64(len(base)) {
+		return nil, fmt.Errorf("Git delta base size mismatch")
+	}
+	out := make([]byte, 0, dstSize)
+	bad := fmt.Errorf("Corrupt git delta data")
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// copy from base; the low bits say which offset and size bytes follow
+			var offset, size int
+			for i := uint(0); i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, bad
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					size |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, bad
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0:
+			// insert literal data
+			if int(op) > len(delta) {
+				return nil, bad
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, bad
+		}
+	}
+	if int64(len(out)) != dstSize {
+		return nil, bad
+	}
+	return out, nil
+}
+
+// Follow tag and commit objects to find the ID of a tree object.
+func (self *gitRepo) peelToTree(sha string) (string, error) {
+	for depth := 0; depth < maxGitRefDepth; depth++ {
+		typ, _, data, err := self.readObject(sha, false)
+		if err != nil {
+			return "", err
+		}
+		prefix := ""
+		switch typ {
+		case gitTree:
+			return sha, nil
+		case gitCommit:
+			prefix = "tree "
+		case gitTag:
+			prefix = "object "
+		default:
+			return "", fmt.Errorf("Git object %s is not a tree, commit or tag", sha)
+		}
+		// the referenced object is in a header line of the commit or tag
+		sha = ""
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" {
+				break
+			}
+			if strings.HasPrefix(line, prefix) {
+				sha = strings.TrimPrefix(line, prefix)
+				break
+			}
+		}
+		if !gitShaPat.MatchString(sha) {
+			return "", fmt.Errorf("Can't find tree in git object")
+		}
+	}
+	return "", fmt.Errorf("Too many levels of git tags")
+}
+
+// One entry of a git tree object
+type gitTreeEntry struct {
+	mode string // octal file mode
+	name string // file name
+	sha  string // hex object ID
+}
+
+// Parse the data of a git tree object. Each entry is "<mode> <name>\0"
+// followed by a 20 byte binary object ID.
+func parseGitTree(data []byte) ([]gitTreeEntry, error) {
+	var out []gitTreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return nil, fmt.Errorf("Corrupt git tree object")
+		}
+		out = append(out, gitTreeEntry{
+			mode: string(data[:sp]),
+			name: string(data[sp+1 : nul]),
+			sha:  hex.EncodeToString(data[nul+1 : nul+21]),
+		})
+		data = data[nul+21:]
+	}
+	return out, nil
+}
+
+// Convert a git tree entry mode to a file mode string, like the modestr column.
+func gitModeStr(mode string) string {
+	switch mode {
+	case "40000", "040000", "160000":
+		return (os.ModeDir | 0755).String()
+	case "120000":
+		return (os.ModeSymlink | 0777).String()
+	case "100755":
+		return os.FileMode(0755).String()
+	default:
+		return os.FileMode(0644).String()
+	}
+}
+
+// Scan a "root" that names a tree in a git repository, like "repo.git@v1.2".
+// Entries are created for the tree contents like a file system scan, except
+// that there are no modification times or owners.
+func (self *Context) processGitRoot(repoDir, ref string) error {
+	repo, err := openGitRepo(repoDir)
+	if err != nil {
+		return err
+	}
+	defer repo.close()
+	sha, err := repo.resolveRef(ref, 0)
+	if err != nil {
+		return err
+	}
+	sha, err = repo.peelToTree(sha)
+	if err != nil {
+		return err
+	}
+	_, err = self.scanGitTree(repo, sha, ".")
+	return err
+}
+
+// Create a file entry for an item in a git tree.
+func (self *Context) newGitEntry(relPath, mode string, size int64) fileEntry {
+	entry := newFileEntry()
+	entry.setStringField(ColPath, relPath)
+	entry.setNumericField(ColSize, size)
+	modeStr := gitModeStr(mode)
+	if self.needsCol(ColModestr) {
+		entry.setStringField(ColModestr, modeStr)
+	}
+	if self.needsCol(ColFileType) {
+		entry.setStringField(ColFileType, modeStrToFileType(modeStr))
+	}
+	return entry
+}
+
+// Calculate the needed digest, entropy and grep columns for a git blob.
+// Nonregular files get empty values, like in file system scans.
+func (self *Context) calcGitDigests(repo *gitRepo, sha string, regular bool, entry fileEntry) error {
+	var data []byte
+	// read the blob the first time it's needed
+	readBlob := func() error {
+		if data != nil {
+			return nil
+		}
+		_, _, blob, err := repo.readObject(sha, false)
+		data = blob
+		return err
+	}
+	for _, col := range digestColumns {
+		if !self.needsCol(col) {
+			continue
+		}
+		switch {
+		case !regular:
+			entry.setStringField(col, "")
+		case col == ColGitBlob:
+			// the object ID is already known
+			entry.setStringField(col, sha)
+		default:
+			if err := readBlob(); err != nil {
+				return err
+			}
+			hash := hashes[col]()
+			hash.Write(data)
+			entry.setStringField(col, hex.EncodeToString(hash.Sum(nil)))
+		}
+	}
+	if self.needsCol(ColEntropy) {
+		if !regular {
+			entry.setStringField(ColEntropy, "")
+		} else {
+			if err := readBlob(); err != nil {
+				return err
+			}
+			counter := entropyCounter{limit: self.EntropySample}
+			counter.Write(data)
+			entry.setStringField(ColEntropy, formatEntropy(counter.entropy()))
+		}
+	}
+	if self.needsCol(ColGrepCount) || self.needsCol(ColGrepLine) {
+		count, line := int64(0), ""
+		if regular {
+			if err := readBlob(); err != nil {
+				return err
+			}
+			var err error
+			count, line, err = grepReader(bytes.NewReader(data), self.Grep, self.GrepMaxBytes, self.GrepBinary)
+			if err != nil {
+				return err
+			}
+		}
+		self.setGrepResult(entry, count, line)
+	}
+	return nil
+}
+
+// Recursively scan a git tree object, adding entries to the context. relPath
+// is the path of the tree relative to the root. Returns the cumulative size
+// of the blobs in the tree.
+func (self *Context) scanGitTree(repo *gitRepo, sha, relPath string) (int64, error) {
+	size := int64(0)
+	_, _, data, err := repo.readObject(sha, false)
+	if err != nil {
+		return 0, err
+	}
+	items, err := parseGitTree(data)
+	if err != nil {
+		return 0, err
+	}
+ItemLoop:
+	for _, item := range items {
+		// skip if item matches an exclude pattern
+		for _, regex := range self.Excludes {
+			if regex.MatchString(item.name) {
+				continue ItemLoop
+			}
+		}
+		itemPath := path.Join(relPath, item.name)
+		switch item.mode {
+		case "40000", "040000":
+			// subtree; descend into it unless pruned
+			dirEntry := self.newGitEntry(itemPath+"/", item.mode, 0)
+			match, notNull := self.pruneFilter.filter(dirEntry)
+			self.checkNullCompare(notNull)
+			if match {
+				s, err := self.scanGitTree(repo, item.sha, itemPath)
+				if err != nil {
+					return 0, err
+				}
+				size += s
+			}
+		case "160000":
+			// submodule commit; the contents aren't in this repository
+			if !self.RegularOnly {
+				self.indexEntry(self.newGitEntry(itemPath+"/", item.mode, 0))
+			}
+		default:
+			regular := item.mode != "120000"
+			if self.RegularOnly && !regular {
+				continue
+			}
+			blobSize := int64(0)
+			if regular {
+				_, blobSize, _, err = repo.readObject(item.sha, true)
+				if err != nil {
+					return 0, err
+				}
+			}
+			entry := self.newGitEntry(itemPath, item.mode, blobSize)
+			scanned, _ := self.scanStats.total()
+			self.outTempf(0, "Git(%d) %s", scanned, itemPath)
+			if self.indexEntry(entry) {
+				if err = self.calcGitDigests(repo, item.sha, regular, entry); err != nil {
+					return 0, err
+				}
+			}
+			size += blobSize
+		}
+	}
+	if !self.RegularOnly {
+		// add an entry for this tree, like for directories in file system scans
+		entry := self.newGitEntry(relPath+"/", "40000", size)
+		if self.indexEntry(entry) {
+			self.calcGitDigests(repo, "", false, entry)
+		}
+	}
+	return size, nil
+}
@@ -0,0 +1,293 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// Compute the ID of a git object
+func gitObjectId(typ string, data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", typ, len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Compress data with zlib
+func zlibBytes(data []byte) []byte {
+	buf := bytes.Buffer{}
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// Write a loose object to a test repository, return its ID
+func writeLooseObject(t *testing.T, gitDir, typ string, data []byte) string {
+	sha := gitObjectId(typ, data)
+	dir := filepath.Join(gitDir, "objects", sha[:2])
+	os.MkdirAll(dir, 0755)
+	raw := append([]byte(fmt.Sprintf("%s %d\x00", typ, len(data))), data...)
+	if err := ioutil.WriteFile(filepath.Join(dir, sha[2:]), zlibBytes(raw), 0644); err != nil {
+		t.Error("Couln't write git object for unit test")
+	}
+	return sha
+}
+
+// Encode a pack object header
+func packObjectHeader(typ int, size int) []byte {
+	b := byte(typ<<4) | byte(size&15)
+	size >>= 4
+	out := []byte{}
+	for size > 0 {
+		out = append(out, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	return append(out, b)
+}
+
+// Encode a git delta size
+func deltaSize(size int) []byte {
+	out := []byte{}
+	for size >= 0x80 {
+		out = append(out, byte(size&0x7f)|0x80)
+		size >>= 7
+	}
+	return append(out, byte(size))
+}
+
+// Write a pack file holding a base blob and a second blob stored as an offset
+// delta of the first, plus its index. Returns the IDs of the two blobs.
+func writeTestPack(t *testing.T, gitDir string, base, second []byte, copyLen int) (string, string) {
+	pack := bytes.Buffer{}
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+
+	// base object
+	baseOffset := pack.Len()
+	pack.Write(packObjectHeader(gitBlob, len(base)))
+	pack.Write(zlibBytes(base))
+
+	// delta object: copy a prefix of the base, then insert the rest
+	delta := append(deltaSize(len(base)), deltaSize(len(second))...)
+	delta = append(delta, 0x90, byte(copyLen)) // copy, one size byte, offset 0
+	delta = append(delta, byte(len(second)-copyLen))
+	delta = append(delta, second[copyLen:]...)
+	deltaOffset := pack.Len()
+	pack.Write(packObjectHeader(gitOfsDelta, len(delta)))
+	pack.WriteByte(byte(deltaOffset - baseOffset)) // small offset fits in one byte
+	pack.Write(zlibBytes(delta))
+	pack.Write(make([]byte, 20)) // checksum is not verified
+
+	// build the index
+	shas := []string{gitObjectId("blob", base), gitObjectId("blob", second)}
+	offsets := map[string]uint32{shas[0]: uint32(baseOffset), shas[1]: uint32(deltaOffset)}
+	sorted := append([]string{}, shas...)
+	sort.Strings(sorted)
+	idx := bytes.Buffer{}
+	idx.WriteString("\xfftOc")
+	binary.Write(&idx, binary.BigEndian, uint32(2))
+	for i := 0; i < 256; i++ {
+		n := 0
+		for _, sha := range sorted {
+			b, _ := hex.DecodeString(sha[:2])
+			if int(b[0]) <= i {
+				n++
+			}
+		}
+		binary.Write(&idx, binary.BigEndian, uint32(n))
+	}
+	for _, sha := range sorted {
+		b, _ := hex.DecodeString(sha)
+		idx.Write(b)
+	}
+	idx.Write(make([]byte, 4*len(sorted))) // CRCs are not verified
+	for _, sha := range sorted {
+		binary.Write(&idx, binary.BigEndian, offsets[sha])
+	}
+
+	packDir := filepath.Join(gitDir, "objects", "pack")
+	os.MkdirAll(packDir, 0755)
+	err1 := ioutil.WriteFile(filepath.Join(packDir, "pack-test.pack"), pack.Bytes(), 0644)
+	err2 := ioutil.WriteFile(filepath.Join(packDir, "pack-test.idx"), idx.Bytes(), 0644)
+	if err1 != nil || err2 != nil {
+		t.Error("Couln't write git pack for unit test")
+	}
+	return shas[0], shas[1]
+}
+
+// Encode a git tree entry
+func treeEntry(mode, name, sha string) []byte {
+	b, _ := hex.DecodeString(sha)
+	return append([]byte(mode+" "+name+"\x00"), b...)
+}
+
+func Test_applyGitDelta(t *testing.T) {
+	base := []byte("hello world")
+	var tests = []struct {
+		delta   []byte
+		want    string
+		wantErr string
+	}{
+		{[]byte{11, 5, 0x90, 5}, "hello", ""},                        // copy
+		{[]byte{11, 3, 3, 'a', 'b', 'c'}, "abc", ""},                 // insert
+		{[]byte{11, 6, 0x91, 6, 5, 1, '!'}, "world!", ""},            // copy with offset plus insert
+		{[]byte{10, 5, 0x90, 5}, "", "Git delta base size mismatch"}, // bad base size
+		{[]byte{11, 5, 0x90, 20}, "", "Corrupt git delta"},           // copy past end
+		{[]byte{11, 5, 5, 'a'}, "", "Corrupt git delta"},             // short insert
+		{[]byte{11, 4, 0x90, 5}, "", "Corrupt git delta"},            // wrong result size
+	}
+	for _, test := range tests {
+		got, err := applyGitDelta(base, test.delta)
+		checkValErr1(t, test.want, string(got), test.wantErr, err)
+	}
+}
+
+func Test_parseGitTree(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	data := append(treeEntry("100644", "a b", sha), treeEntry("40000", "d", sha)...)
+	got, err := parseGitTree(data)
+	want := []gitTreeEntry{{"100644", "a b", sha}, {"40000", "d", sha}}
+	checkValErr1(t, want, got, "", err)
+
+	_, err = parseGitTree(data[:len(data)-1])
+	checkValErr1(t, nil, nil, "Corrupt git tree", err)
+}
+
+func Test_gitModeStr(t *testing.T) {
+	checkVal(t, "-rw-r--r--", gitModeStr("100644"))
+	checkVal(t, "-rwxr-xr-x", gitModeStr("100755"))
+	checkVal(t, "Lrwxrwxrwx", gitModeStr("120000"))
+	checkVal(t, "drwxr-xr-x", gitModeStr("40000"))
+}
+
+func Test_splitGitRoot(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	os.MkdirAll(filepath.Join(dirPath, "repo", ".git", "objects"), 0755)
+
+	var tests = []struct {
+		arg      string
+		wantRepo string
+		wantRef  string
+		wantOk   bool
+	}{
+		{dirPath + "/repo@v1.2", dirPath + "/repo", "v1.2", true},
+		{dirPath + "/repo@", dirPath + "/repo", "HEAD", true},
+		{dirPath + "/repo", "", "", false},
+		{dirPath + "@v1.2", "", "", false},
+	}
+	for _, test := range tests {
+		repo, ref, ok := splitGitRoot(test.arg)
+		checkVal(t, test.wantRepo, repo)
+		checkVal(t, test.wantRef, ref)
+		checkVal(t, test.wantOk, ok)
+	}
+}
+
+func Test_Context_processGitRoot(t *testing.T) {
+	// create a bare repository with loose objects and a pack file
+	gitDir, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(gitDir) }()
+
+	blobA := writeLooseObject(t, gitDir, "blob", []byte("A"))
+	base := []byte("the quick brown fox")
+	blobB, blobC := writeTestPack(t, gitDir, base, []byte("the quick red fox!"), 10)
+	subTree := writeLooseObject(t, gitDir, "tree", treeEntry("100755", "c", blobC))
+	tree := writeLooseObject(t, gitDir, "tree", bytes.Join([][]byte{
+		treeEntry("100644", "a", blobA),
+		treeEntry("120000", "b", blobB),
+		treeEntry("40000", "x", subTree),
+	}, nil))
+	commit := writeLooseObject(t, gitDir, "commit", []byte("tree "+tree+"\nauthor x\n\nmsg\n"))
+	tag := writeLooseObject(t, gitDir, "tag", []byte("object "+commit+"\ntype commit\n\nmsg\n"))
+	os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0755)
+	ioutil.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/master\n"), 0644)
+	ioutil.WriteFile(filepath.Join(gitDir, "refs", "heads", "master"), []byte(commit+"\n"), 0644)
+	ioutil.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte("# pack-refs\n"+tag+" refs/tags/v1\n"), 0644)
+
+	want := []fileEntry{
+		{ColPath: "a", ColSize: int64(1), ColModestr: "-rw-r--r--", ColGitBlob: blobA},
+		{ColPath: "b", ColSize: int64(0), ColModestr: "Lrwxrwxrwx", ColGitBlob: ""},
+		{ColPath: "x/c", ColSize: int64(18), ColModestr: "-rwxr-xr-x", ColGitBlob: blobC},
+		{ColPath: "x/", ColSize: int64(18), ColModestr: "drwxr-xr-x", ColGitBlob: ""},
+		{ColPath: "./", ColSize: int64(19), ColModestr: "drwxr-xr-x", ColGitBlob: ""},
+	}
+	for _, ref := range []string{"", "HEAD", "master", "v1", commit} {
+		ctx := NewContext()
+		ctx.neededCols[ColModestr] = true
+		ctx.neededCols[ColGitBlob] = true
+		err = ctx.processGitRoot(gitDir, ref)
+		if ref == "" {
+			checkValErr1(t, nil, nil, "Can't resolve git ref", err)
+			continue
+		}
+		checkValErr1(t, want, ctx.entries, "", err)
+		checkVal(t, int64(5), ctx.indexStats.counts[0])
+		checkVal(t, int64(19), ctx.indexStats.sizes[0])
+	}
+
+	// other digests need the blob contents, including from the delta
+	ctx := NewContext()
+	ctx.neededCols[ColMd5] = true
+	ctx.RegularOnly = true
+	err = ctx.processGitRoot(gitDir, "master")
+	want = []fileEntry{
+		{ColPath: "a", ColSize: int64(1), ColMd5: "7fc56270e7a70fa81a5935b72eacbe29"},
+		{ColPath: "x/c", ColSize: int64(18), ColMd5: "b0d55e21e06e2b88c74c9191e8762145"},
+	}
+	checkValErr1(t, want, ctx.entries, "", err)
+
+	// grep searches the blobs too
+	ctx = NewContext()
+	ctx.neededCols[ColGrepCount] = true
+	ctx.neededCols[ColGrepLine] = true
+	ctx.Grep = regexp.MustCompile("red")
+	err = ctx.processGitRoot(gitDir, "master")
+	want = []fileEntry{
+		{ColPath: "a", ColSize: int64(1), ColGrepCount: int64(0), ColGrepLine: ""},
+		{ColPath: "b", ColSize: int64(0), ColGrepCount: int64(0), ColGrepLine: ""},
+		{ColPath: "x/c", ColSize: int64(18), ColGrepCount: int64(1), ColGrepLine: "the quick red fox!"},
+		{ColPath: "x/", ColSize: int64(18), ColGrepCount: int64(0), ColGrepLine: ""},
+		{ColPath: "./", ColSize: int64(19), ColGrepCount: int64(0), ColGrepLine: ""},
+	}
+	checkValErr1(t, want, ctx.entries, "", err)
+}
@@ -0,0 +1,145 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+)
+
+// Files with a null byte in this many leading bytes are considered binary
+const binaryCheckSize = 8000
+
+// Only this many bytes of each line are searched, so that a long file with
+// no line breaks isn't read into memory
+const maxGrepLine = 64 * 1024
+
+// Return true if the given leading data of a file looks like binary data.
+func looksBinary(head []byte) bool {
+	if len(head) > binaryCheckSize {
+		head = head[:binaryCheckSize]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// Search the data from a reader line by line for a regular expression.
+// Returns the number of matching lines and the first matching line, without
+// its line terminator. If maxBytes is greater than zero, only that many bytes
+// are read. Binary data is skipped (zero matches) unless searchBinary is set.
+// Lines are cut at maxGrepLine bytes.
+func grepReader(r io.Reader, regex *regexp.Regexp, maxBytes int64, searchBinary bool) (int64, string, error) {
+	if maxBytes > 0 {
+		r = io.LimitReader(r, maxBytes)
+	}
+	br := bufio.NewReaderSize(r, binaryCheckSize)
+	if !searchBinary {
+		head, err := br.Peek(binaryCheckSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return 0, "", err
+		}
+		if looksBinary(head) {
+			return 0, "", nil
+		}
+	}
+	count := int64(0)
+	first := ""
+	var carry []byte // the start of a line longer than the read buffer
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			if room := maxGrepLine - len(carry); room > 0 {
+				if len(line) > room {
+					line = line[:room]
+				}
+				carry = append(carry, line...)
+			}
+			continue
+		}
+		if carry != nil {
+			if room := maxGrepLine - len(carry); len(line) > room {
+				line = line[:room]
+			}
+			line = append(carry, line...)
+			carry = carry[:0]
+		}
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\r\n")
+			if regex.Match(line) {
+				if count == 0 {
+					first = string(line)
+				}
+				count++
+			}
+		}
+		if err == io.EOF {
+			return count, first, nil
+		}
+		if err != nil {
+			return count, first, err
+		}
+	}
+}
+
+// Set the needed grep columns of an entry to a match count and first line.
+func (self *Context) setGrepResult(entry fileEntry, count int64, line string) {
+	if self.needsCol(ColGrepCount) {
+		entry.setNumericField(ColGrepCount, count)
+	}
+	if self.needsCol(ColGrepLine) {
+		entry.setStringField(ColGrepLine, line)
+	}
+}
+
+// Compute the grep columns for a file by searching its contents for the
+// --grep pattern. Nonregular files get zero matches and an empty line.
+func (self *Context) calcGrepFile(root string, entry fileEntry) {
+	// get the file name and open it
+	relPath, ok := entry.getStringField(ColPath)
+	if !ok {
+		self.onError("Missing path in file entry: ")
+		return
+	}
+	filePath := myJoin(root, relPath)
+	fi, err := self.statFile(filePath)
+	if err != nil {
+		self.onError("Can't get file information: ", err)
+		return
+	}
+	if !fi.Mode().IsRegular() {
+		self.setGrepResult(entry, 0, "")
+		return
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		self.onError("Can't open file for reading: ", err)
+		return
+	}
+	defer file.Close()
+
+	self.outTempf(0, "grep %s", filePath)
+	count, line, err := grepReader(file, self.Grep, self.GrepMaxBytes, self.GrepBinary)
+	if err != nil {
+		self.onError("Can't read file for grep: ", err)
+		return
+	}
+	self.setGrepResult(entry, count, line)
+}
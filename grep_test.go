@@ -0,0 +1,90 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_grepReader(t *testing.T) {
+	var tests = []struct {
+		data      string
+		pat       string
+		maxBytes  int64
+		binary    bool
+		wantCount int64
+		wantLine  string
+	}{
+		{"", "foo", 0, false, 0, ""},
+		{"foo\nbar\nfood\n", "foo", 0, false, 2, "foo"},
+		{"a\r\nxfoo\r\n", "foo$", 0, false, 1, "xfoo"},
+		{"foo", "^foo$", 0, false, 1, "foo"},       // no final newline
+		{"bar\nfoo\n", "foo", 5, false, 0, ""},     // beyond max bytes
+		{"bar\nfoo\n", "fo", 6, false, 1, "fo"},    // partial line up to max bytes
+		{"foo\x00\nfoo\n", "foo", 0, false, 0, ""}, // binary skipped
+		{"foo\x00\nfoo\n", "foo", 0, true, 2, "foo\x00"},
+		{strings.Repeat("x\n", 5000) + "\x00foo\n", "foo", 0, false, 1, "\x00foo"}, // null after check size
+		// long lines are cut at maxGrepLine bytes
+		{strings.Repeat("x", 20000) + "foo\nfoo\n", "^x+foo$", 0, false, 1, strings.Repeat("x", 20000) + "foo"},
+		{strings.Repeat("x", maxGrepLine) + "foo\nfoo\n", "foo", 0, false, 1, "foo"},
+		{strings.Repeat("x", maxGrepLine+10000) + "\n", "^x+$", 0, false, 1, strings.Repeat("x", maxGrepLine)},
+	}
+	for _, test := range tests {
+		regex := regexp.MustCompile(test.pat)
+		count, line, err := grepReader(strings.NewReader(test.data), regex, test.maxBytes, test.binary)
+		checkValErr1(t, test.wantCount, count, "", err)
+		checkVal(t, test.wantLine, line)
+	}
+}
+
+func Test_Context_calcGrepFile(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	ioutil.WriteFile(filepath.Join(dirPath, "a"), []byte("one\nTODO: two\nthree TODO\n"), 0644)
+
+	ctx := NewContext()
+	ctx.Grep = regexp.MustCompile("TODO")
+	ctx.neededCols[ColGrepCount] = true
+	ctx.neededCols[ColGrepLine] = true
+	var tests = []struct {
+		path      string
+		wantCount int64
+		wantLine  string
+	}{
+		{"a", 2, "TODO: two"},
+		{".", 0, ""},
+	}
+	for _, test := range tests {
+		entry := fileEntry{ColPath: test.path}
+		ctx.calcGrepFile(dirPath, entry)
+		count, _ := entry.getNumericField(ColGrepCount)
+		line, _ := entry.getStringField(ColGrepLine)
+		checkVal(t, test.wantCount, count)
+		checkVal(t, test.wantLine, line)
+	}
+}
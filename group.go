@@ -0,0 +1,366 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of aggregate values computed for each group of entries
+const (
+	aggCount    = iota // number of entries in the group
+	aggSum             // sum of a numeric column
+	aggMin             // smallest value of a column
+	aggMax             // largest value of a column
+	aggAvg             // average of a numeric column, rounded to an integer
+	aggDistinct        // number of distinct values of a column
+)
+
+// Names of the aggregate kinds in aggregate specs
+var aggKinds = map[string]int{
+	"count": aggCount, "sum": aggSum, "min": aggMin, "max": aggMax, "avg": aggAvg,
+	"earliest": aggMin, "latest": aggMax, "distinct": aggDistinct,
+}
+
+// An aggregate value computed over the entries in each group for --group-by.
+type Aggregate struct {
+	name string // the spec this was parsed from, like "sum:size"
+	kind int    // the kind of aggregate value
+	col  Column // the column aggregated, except for count
+}
+
+// Parse a comma-separated list of aggregate specs, like "count,sum:size".
+// Each spec but "count" names a kind and a column, separated by a colon.
+func ParseAggregates(list string) ([]Aggregate, error) {
+	var aggs []Aggregate
+	for _, spec := range strings.Split(list, ",") {
+		parts := strings.SplitN(spec, ":", 2)
+		kind, ok := aggKinds[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("Bad aggregate '%s'; must be count, or one of sum, min, max, avg, earliest, latest or distinct, ':' and a column", spec)
+		}
+		agg := Aggregate{name: spec, kind: kind}
+		if kind == aggCount {
+			if len(parts) > 1 {
+				return nil, fmt.Errorf("The count aggregate doesn't take a column: '%s'", spec)
+			}
+			aggs = append(aggs, agg)
+			continue
+		}
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Aggregate '%s' needs a column, like '%s:size'", spec, spec)
+		}
+		if agg.col, ok = colIndex[parts[1]]; !ok {
+			return nil, fmt.Errorf("Bad column name in aggregate '%s'", spec)
+		}
+		if (kind == aggSum || kind == aggAvg) && !agg.col.isNumeric() {
+			return nil, fmt.Errorf("Aggregate '%s' needs a numeric column", spec)
+		}
+		aggs = append(aggs, agg)
+	}
+	return aggs, nil
+}
+
+// A key to sort groups by: a group column or an aggregate, and whether to
+// sort in reverse
+type groupSortKey struct {
+	col     int  // index in the group columns, or -1 for an aggregate
+	agg     int  // index in the aggregates, if col < 0
+	inverse bool // true to sort in reverse
+}
+
+// Accumulates an aggregate value over the entries in a group
+type aggState struct {
+	count    int64                // number of entries with a value
+	sum      int64                // sum of numeric values
+	val      interface{}          // min or max value so far
+	distinct map[interface{}]bool // set of values seen
+}
+
+// Add a value from an entry to the state of an aggregate.
+func (self *aggState) add(agg *Aggregate, val interface{}) {
+	self.count++
+	switch agg.kind {
+	case aggSum, aggAvg:
+		self.sum += val.(int64)
+	case aggMin, aggMax:
+		if self.val == nil || (compareValues(val, self.val) < 0) == (agg.kind == aggMin) {
+			self.val = val
+		}
+	case aggDistinct:
+		if self.distinct == nil {
+			self.distinct = map[interface{}]bool{}
+		}
+		self.distinct[val] = true
+	}
+}
+
+// Return the final value of an aggregate, or nil if there is none.
+func (self *aggState) value(agg *Aggregate, entries int64) interface{} {
+	switch agg.kind {
+	case aggCount:
+		return entries
+	case aggDistinct:
+		return int64(len(self.distinct))
+	case aggMin, aggMax:
+		return self.val
+	}
+	if self.count == 0 {
+		return nil // no values to sum
+	}
+	if agg.kind == aggAvg {
+		return (self.sum + self.count/2) / self.count
+	}
+	return self.sum
+}
+
+// Compare two non-nil aggregate values, which are both int64 or both string.
+func compareValues(v1, v2 interface{}) int {
+	if n1, ok := v1.(int64); ok {
+		n2 := v2.(int64)
+		switch {
+		case n1 < n2:
+			return -1
+		case n1 > n2:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(v1.(string), v2.(string))
+}
+
+// The entries with the same values of the group columns
+type entryGroup struct {
+	first  fileEntry     // the first entry, which has the group column values
+	count  int64         // number of entries in the group
+	states []aggState    // the state of each aggregate
+	values []interface{} // the final value of each aggregate
+}
+
+// Return true if output is grouped with --group-by.
+func (self *Context) grouping() bool {
+	return len(self.GroupCols.cols) > 0
+}
+
+// Return the names of the grouped output columns: the group columns, then
+// the aggregates.
+func (self *Context) groupColumnNames() []string {
+	var names []string
+	for _, col := range self.GroupCols.cols {
+		names = append(names, col.String())
+	}
+	for _, agg := range self.Aggregates {
+		names = append(names, agg.name)
+	}
+	return names
+}
+
+// Parse the --group-sort keys: group column names or aggregate specs,
+// prefixed with '/' to sort in reverse. Aggregates not already in the output
+// are added to it. With no keys, groups are sorted by the group columns.
+func (self *Context) parseGroupSort() ([]groupSortKey, error) {
+	var keys []groupSortKey
+	if self.GroupSort == "" {
+		for i := range self.GroupCols.cols {
+			keys = append(keys, groupSortKey{col: i})
+		}
+		return keys, nil
+	}
+	for _, name := range strings.Split(self.GroupSort, ",") {
+		key := groupSortKey{col: -1}
+		if strings.HasPrefix(name, "/") {
+			key.inverse = true
+			name = name[1:]
+		}
+		for i, col := range self.GroupCols.cols {
+			if c, ok := colIndex[name]; ok && c == col {
+				key.col = i
+			}
+		}
+		if key.col < 0 {
+			key.agg = -1
+			for i, agg := range self.Aggregates {
+				if agg.name == name {
+					key.agg = i
+				}
+			}
+			if key.agg < 0 {
+				aggs, err := ParseAggregates(name)
+				if err != nil {
+					return nil, fmt.Errorf("--group-sort key '%s' is not a group column or aggregate", name)
+				}
+				self.Aggregates = append(self.Aggregates, aggs...)
+				key.agg = len(self.Aggregates) - 1
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Collect the entries into groups with the same values of the group columns,
+// in order of their first entries, and compute the aggregates of each group.
+func (self *Context) groupEntries(entries []fileEntry) []*entryGroup {
+	var groups []*entryGroup
+	index := map[string]*entryGroup{}
+	for _, entry := range entries {
+		key := groupKey(entry, self.GroupCols.cols)
+		group := index[key]
+		if group == nil {
+			group = &entryGroup{first: entry, states: make([]aggState, len(self.Aggregates))}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.count++
+		for i := range self.Aggregates {
+			agg := &self.Aggregates[i]
+			if agg.kind == aggCount {
+				continue
+			}
+			if agg.col == ColSize && strings.HasSuffix(entryPath(entry), "/") {
+				// directories count as zero size, as in the output stats
+				group.states[i].add(agg, int64(0))
+			} else if val, ok := entry.getField(agg.col); ok {
+				group.states[i].add(agg, val)
+			}
+		}
+	}
+	for _, group := range groups {
+		for i := range self.Aggregates {
+			group.values = append(group.values, group.states[i].value(&self.Aggregates[i], group.count))
+		}
+	}
+	return groups
+}
+
+// Return a key for the values of the given columns of an entry. Entries
+// have the same key if they have the same values, and the same null values.
+func groupKey(entry fileEntry, cols []Column) string {
+	key := ""
+	for _, col := range cols {
+		val, ok := entry.getField(col)
+		key += fmt.Sprintf("%v\x00%v\x00", ok, val)
+	}
+	return key
+}
+
+// Sort groups by the given keys. Null aggregate values sort first.
+func (self *Context) sortGroups(groups []*entryGroup, keys []groupSortKey) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		for _, key := range keys {
+			d := 0
+			if key.col >= 0 {
+				d, _ = groups[i].first.compare(groups[j].first, self.GroupCols.cols[key.col:key.col+1])
+			} else {
+				v1, v2 := groups[i].values[key.agg], groups[j].values[key.agg]
+				switch {
+				case v1 == nil && v2 == nil:
+				case v1 == nil:
+					d = -1
+				case v2 == nil:
+					d = 1
+				default:
+					d = compareValues(v1, v2)
+				}
+			}
+			if key.inverse {
+				d = -d
+			}
+			if d != 0 {
+				return d < 0
+			}
+		}
+		return false
+	})
+}
+
+// Format an aggregate value for output, like a column value. Values of the
+// mtime column are shown in the output timezone.
+func (self *Context) formatAggregate(agg *Aggregate, val interface{}, lastCol bool) string {
+	switch v := val.(type) {
+	case int64:
+		return self.formatNumber(v)
+	case string:
+		if agg.col == ColMtime {
+			v = self.adjustOutputTimezone(v)
+		}
+		return escapeField(v, true, lastCol)
+	}
+	return escapeField("", false, lastCol)
+}
+
+// Output the filtered entries grouped by the group columns, with a line (or
+// JSON object) for each group, and update the output statistics.
+func (self *Context) outputGroups(entries []fileEntry, keys []groupSortKey, indent, separator string) {
+	for _, e := range entries {
+		self.updateOutputStats(e)
+	}
+	groups := self.groupEntries(entries)
+	self.sortGroups(groups, keys)
+
+	if self.JsonOut {
+		names := self.groupColumnNames()
+		for j, group := range groups {
+			m := map[string]interface{}{}
+			for i, col := range self.GroupCols.cols {
+				m[names[i]], _ = group.first.getField(col)
+			}
+			for i := range self.Aggregates {
+				m[self.Aggregates[i].name] = group.values[i]
+			}
+			json, err := json.MarshalIndent(m, "    ", "    ")
+			if err != nil {
+				self.onError("Error encoding JSON output: ", err)
+				continue
+			}
+			sep := ","
+			if j == len(groups)-1 {
+				sep = ""
+			}
+			for _, line := range strings.Split("    "+string(json)+sep, "\n") {
+				self.outf(-1, "%s", line)
+			}
+		}
+		return
+	}
+
+	var numeric []bool
+	for _, col := range self.GroupCols.cols {
+		numeric = append(numeric, col.isNumeric())
+	}
+	for _, agg := range self.Aggregates {
+		numeric = append(numeric, agg.kind != aggMin && agg.kind != aggMax || agg.col.isNumeric())
+	}
+	var lines [][]string
+	for _, group := range groups {
+		var fields []string
+		for i, col := range self.GroupCols.cols {
+			fields = append(fields, group.first.formatField(self, col, -1, self.Plain0 || i == len(numeric)-1))
+		}
+		for i := range self.Aggregates {
+			last := len(fields) == len(numeric)-1
+			fields = append(fields, self.formatAggregate(&self.Aggregates[i], group.values[i], self.Plain0 || last))
+		}
+		lines = append(lines, fields)
+	}
+	self.outputTable(lines, numeric, indent, separator)
+}
@@ -0,0 +1,99 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_ParseAggregates(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  []Aggregate
+		err   string
+	}{
+		{"count", []Aggregate{{"count", aggCount, 0}}, ""},
+		{"sum:size,latest:mtime,distinct:u", []Aggregate{{"sum:size", aggSum, ColSize},
+			{"latest:mtime", aggMax, ColMtime}, {"distinct:u", aggDistinct, ColUser}}, ""},
+		{"total", nil, "Bad aggregate 'total'"},
+		{"count:size", nil, "The count aggregate doesn't take a column"},
+		{"max", nil, "Aggregate 'max' needs a column"},
+		{"min:nope", nil, "Bad column name in aggregate"},
+		{"avg:path", nil, "Aggregate 'avg:path' needs a numeric column"},
+	}
+	for _, test := range tests {
+		got, err := ParseAggregates(test.input)
+		checkValErr1(t, test.want, got, test.err, err)
+	}
+}
+
+func Test_Context_groupEntries(t *testing.T) {
+	ctx := NewContext()
+	ctx.GroupCols = ColSelector{cols: []Column{ColExt}}
+	ctx.Aggregates, _ = ParseAggregates("count,sum:size,avg:size,min:mtime,max:size,distinct:user,sum:uid")
+	entries := []fileEntry{
+		{ColPath: "a.go", ColSize: int64(1), ColMtime: "2017-01-02T00:00:00Z", ColUser: "joe"},
+		{ColPath: "b.txt", ColSize: int64(10)},
+		{ColPath: "c.go", ColSize: int64(4), ColMtime: "2017-01-01T00:00:00Z", ColUser: "joe"},
+		{ColPath: "d.go", ColSize: int64(2), ColUser: "sue"},
+		{ColPath: "e.go/", ColSize: int64(4096)},
+	}
+	groups := ctx.groupEntries(entries)
+	checkVal(t, 3, len(groups))
+	checkVal(t, []interface{}{int64(3), int64(7), int64(2), "2017-01-01T00:00:00Z", int64(4), int64(2), nil}, groups[0].values)
+	checkVal(t, []interface{}{int64(1), int64(10), int64(10), nil, int64(10), int64(0), nil}, groups[1].values)
+	checkVal(t, []interface{}{int64(1), int64(0), int64(0), nil, int64(0), int64(0), nil}, groups[2].values)
+
+	var got []string
+	for _, agg := range ctx.Aggregates {
+		got = append(got, ctx.formatAggregate(&agg, groups[1].values[len(got)], false))
+	}
+	checkVal(t, []string{"1", "10", "10", `\~`, "10", "0", `\~`}, got)
+}
+
+func Test_Context_parseGroupSort(t *testing.T) {
+	ctx := NewContext()
+	ctx.GroupCols = ColSelector{cols: []Column{ColExt, ColUser}}
+	ctx.Aggregates, _ = ParseAggregates("count")
+	keys, err := ctx.parseGroupSort()
+	checkValErr1(t, []groupSortKey{{0, 0, false}, {1, 0, false}}, keys, "", err)
+
+	ctx.GroupSort = "/count,u,/max:size"
+	keys, err = ctx.parseGroupSort()
+	checkValErr1(t, []groupSortKey{{-1, 0, true}, {1, 0, false}, {-1, 1, true}}, keys, "", err)
+	checkVal(t, []string{"ext", "user", "count", "max:size"}, ctx.groupColumnNames())
+
+	ctx.GroupSort = "size"
+	_, err = ctx.parseGroupSort()
+	checkValErr1(t, nil, nil, "--group-sort key 'size' is not a group column or aggregate", err)
+}
+
+func Test_Context_sortGroups(t *testing.T) {
+	ctx := NewContext()
+	ctx.GroupCols = ColSelector{cols: []Column{ColExt}}
+	ctx.Aggregates, _ = ParseAggregates("count,max:size")
+	g1 := &entryGroup{first: fileEntry{ColPath: "a.b"}, values: []interface{}{int64(2), nil}}
+	g2 := &entryGroup{first: fileEntry{ColPath: "a.c"}, values: []interface{}{int64(1), int64(5)}}
+	g3 := &entryGroup{first: fileEntry{ColPath: "a.a"}, values: []interface{}{int64(2), int64(3)}}
+	groups := []*entryGroup{g1, g2, g3}
+	ctx.sortGroups(groups, []groupSortKey{{col: 0}})
+	checkVal(t, []*entryGroup{g3, g1, g2}, groups)
+	ctx.sortGroups(groups, []groupSortKey{{col: -1, agg: 0, inverse: true}, {col: -1, agg: 1}})
+	checkVal(t, []*entryGroup{g1, g3, g2}, groups)
+}
@@ -0,0 +1,284 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of histogram buckets
+const (
+	histSize = iota // file sizes
+	histAge         // file ages, from the mtime column
+)
+
+// Lengths in seconds of the age histogram units
+var histAgeUnits = map[string]int64{
+	"day":   24 * 3600,
+	"week":  7 * 24 * 3600,
+	"month": 30 * 24 * 3600,
+	"year":  365 * 24 * 3600,
+}
+
+// Key of the histogram bucket for entries with a null value
+const histNullKey = -1
+
+// A histogram of the output entries for --histogram.
+type Histogram struct {
+	kind   int     // the kind of buckets
+	bounds []int64 // boundaries of size buckets; nil for powers of two
+	unit   string  // name of the age bucket unit
+}
+
+// Parse a histogram spec: "size" for buckets of powers of two, "size:" and a
+// comma-separated list of bucket boundaries like "size:1M,100M,1G", or
+// "age:" and a unit of day, week, month or year.
+func ParseHistogram(spec string) (*Histogram, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	switch {
+	case parts[0] == "size" && len(parts) == 1:
+		return &Histogram{kind: histSize}, nil
+	case parts[0] == "size":
+		hist := &Histogram{kind: histSize}
+		for _, s := range strings.Split(parts[1], ",") {
+			n, err := parseSizeLiteral(s)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("Bad histogram size boundary '%s'", s)
+			}
+			if len(hist.bounds) > 0 && n <= hist.bounds[len(hist.bounds)-1] {
+				return nil, fmt.Errorf("Histogram size boundaries must increase: '%s'", spec)
+			}
+			hist.bounds = append(hist.bounds, n)
+		}
+		return hist, nil
+	case parts[0] == "age" && len(parts) == 2 && histAgeUnits[parts[1]] > 0:
+		return &Histogram{kind: histAge, unit: parts[1]}, nil
+	}
+	return nil, fmt.Errorf("Bad histogram '%s'; must be size, size:BOUNDARIES or age:UNIT (day, week, month or year)", spec)
+}
+
+// Return the column that entries are bucketed by.
+func (self *Histogram) column() Column {
+	if self.kind == histAge {
+		return ColMtime
+	}
+	return ColSize
+}
+
+// Return the key of the bucket for an entry. Sizes are bucketed by the
+// number of boundaries at or below them, or by their number of bits. Ages
+// are bucketed by whole units; a file from the future counts as age zero.
+func (self *Histogram) bucket(ctx *Context, entry fileEntry) int {
+	if self.kind == histAge {
+		mtime, ok := entry.getStringField(ColMtime)
+		if !ok {
+			return histNullKey
+		}
+		tm, err := mtimeToTime(mtime)
+		if err != nil {
+			return histNullKey
+		}
+		age := ctx.ageOf(tm)
+		if age < 0 {
+			age = 0
+		}
+		return int(age / histAgeUnits[self.unit])
+	}
+	size, ok := entry.getNumericField(ColSize)
+	if !ok {
+		return histNullKey
+	}
+	if self.bounds != nil {
+		return sort.Search(len(self.bounds), func(i int) bool { return self.bounds[i] > size })
+	}
+	key := 0
+	for ; size > 0; size >>= 1 {
+		key++
+	}
+	return key
+}
+
+// Return the label of a bucket. Size buckets show the smallest size in the
+// bucket and the smallest size in the next one, like "4Ki-8Ki".
+func (self *Histogram) label(key int) string {
+	switch {
+	case key == histNullKey:
+		return escapeField("", false, false)
+	case self.kind == histAge:
+		if key == 1 {
+			return "1 " + self.unit
+		}
+		return fmt.Sprintf("%d %ss", key, self.unit)
+	case self.bounds == nil && key == 0:
+		return "0"
+	case self.bounds == nil:
+		return formatSizeBound(1<<uint(key-1)) + "-" + formatSizeBound(1<<uint(key))
+	case key == 0:
+		return "0-" + formatSizeBound(self.bounds[0])
+	case key == len(self.bounds):
+		return formatSizeBound(self.bounds[key-1]) + "+"
+	}
+	return formatSizeBound(self.bounds[key-1]) + "-" + formatSizeBound(self.bounds[key])
+}
+
+// Format a size with the largest binary (Ki, Mi...) or SI (K, M...)
+// multiplier suffix that represents it exactly, like "64Ki" or "10M".
+func formatSizeBound(n int64) string {
+	for i := 6; i > 0; i-- {
+		binary, si := int64(1)<<uint(10*i), int64(1)
+		for j := 0; j < i; j++ {
+			si *= 1000
+		}
+		suffix := string("KMGTPE"[i-1])
+		switch {
+		case n >= binary && n%binary == 0:
+			return fmt.Sprint(n/binary) + suffix + "i"
+		case n >= si && n%si == 0:
+			return fmt.Sprint(n/si) + suffix
+		}
+	}
+	return fmt.Sprint(n)
+}
+
+// Return the keys of the histogram buckets to show, in order. With given
+// size boundaries, all of the buckets are shown; with powers of two, all of
+// the buckets between the smallest and largest ones used; and with ages,
+// just the buckets used. The null bucket is last, if used.
+func (self *Context) histogramKeys() []int {
+	var used []int
+	for key := range self.histStats {
+		if key != histNullKey {
+			used = append(used, key)
+		}
+	}
+	sort.Ints(used)
+	var keys []int
+	switch {
+	case self.Histogram.bounds != nil:
+		for key := 0; key <= len(self.Histogram.bounds); key++ {
+			keys = append(keys, key)
+		}
+	case self.Histogram.kind == histSize && len(used) > 0:
+		for key := used[0]; key <= used[len(used)-1]; key++ {
+			keys = append(keys, key)
+		}
+	default:
+		keys = used
+	}
+	if self.histStats[histNullKey] != nil {
+		keys = append(keys, histNullKey)
+	}
+	return keys
+}
+
+// Update the histogram with a file being output.
+func (self *Context) updateHistogram(entry fileEntry, size int64) {
+	if self.histStats == nil {
+		self.histStats = map[int]*stats{}
+	}
+	key := self.Histogram.bucket(self, entry)
+	stat := self.histStats[key]
+	if stat == nil {
+		stat = &stats{}
+		self.histStats[key] = stat
+	}
+	stat.update(sideIndex(entry), size)
+}
+
+// Return a bar of up to HistogramBars characters, with a length in
+// proportion to size compared with max. A non-zero size always gets a bar.
+func (self *Context) histogramBar(size, max int64) string {
+	if max <= 0 || size <= 0 {
+		return ""
+	}
+	n := int((size*int64(self.HistogramBars) + max/2) / max)
+	if n == 0 {
+		n = 1
+	}
+	return strings.Repeat("#", n)
+}
+
+// Format the count and size of the files output in each histogram bucket
+// into a 2D array of strings, padded like the summary statistics, with a bar
+// for each side's sizes if requested.
+func (self *Context) calcHistogramInfo() [][]string {
+	sides := self.sidesWithRoots()
+	keys := self.histogramKeys()
+	header := []string{"HISTOGRAM:"}
+	isBar := []bool{false}
+	maxSizes := make([]int64, len(sides))
+	for i, side := range sides {
+		if len(sides) > 1 || side > 0 {
+			name := self.sideName(side)
+			header = append(header, name+":Count", name+":Size")
+		} else {
+			header = append(header, "Count", "Size")
+		}
+		isBar = append(isBar, false, false)
+		if self.HistogramBars > 0 {
+			header = append(header, "")
+			isBar = append(isBar, true)
+		}
+		for _, stat := range self.histStats {
+			if _, size := stat.get(side); size > maxSizes[i] {
+				maxSizes[i] = size
+			}
+		}
+	}
+	out := [][]string{header}
+	for _, key := range keys {
+		stat := self.histStats[key]
+		if stat == nil {
+			stat = &stats{}
+		}
+		line := []string{self.Histogram.label(key)}
+		for i, side := range sides {
+			count, size := stat.get(side)
+			line = append(line, self.formatNumber(count), self.formatNumber(size))
+			if self.HistogramBars > 0 {
+				line = append(line, self.histogramBar(size, maxSizes[i]))
+			}
+		}
+		out = append(out, line)
+	}
+
+	// pad each item to the max width in its column; the bars are aligned
+	// on the left, and the last column isn't padded
+	widths := make([]int, len(header))
+	for _, line := range out {
+		for i, s := range line {
+			if len(s) > widths[i] {
+				widths[i] = len(s)
+			}
+		}
+	}
+	for _, line := range out {
+		for i := range line {
+			switch {
+			case isBar[i] && i < len(line)-1:
+				line[i] = fmt.Sprintf("%-*s", widths[i], line[i])
+			case !isBar[i]:
+				line[i] = fmt.Sprintf("%*s", widths[i], line[i])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseHistogram(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  *Histogram
+		err   string
+	}{
+		{"size", &Histogram{kind: histSize}, ""},
+		{"size:1K,1MiB", &Histogram{kind: histSize, bounds: []int64{1000, 1048576}}, ""},
+		{"age:week", &Histogram{kind: histAge, unit: "week"}, ""},
+		{"size:1M,1K", nil, "Histogram size boundaries must increase"},
+		{"size:x", nil, "Bad histogram size boundary 'x'"},
+		{"size:0", nil, "Bad histogram size boundary '0'"},
+		{"age", nil, "Bad histogram 'age'"},
+		{"age:hour", nil, "Bad histogram 'age:hour'"},
+		{"mtime", nil, "Bad histogram 'mtime'"},
+	}
+	for _, test := range tests {
+		got, err := ParseHistogram(test.input)
+		checkValErr1(t, test.want, got, test.err, err)
+	}
+}
+
+func Test_formatSizeBound(t *testing.T) {
+	var tests = []struct {
+		input int64
+		want  string
+	}{
+		{1, "1"}, {512, "512"}, {1000, "1K"}, {1024, "1Ki"}, {1 << 20, "1Mi"},
+		{10000000, "10M"}, {1500, "1500"}, {3 << 30, "3Gi"}, {1 << 62, "4Ei"},
+	}
+	for _, test := range tests {
+		checkVal(t, test.want, formatSizeBound(test.input))
+	}
+}
+
+func Test_Histogram_bucket(t *testing.T) {
+	ctx := NewContext()
+	ctx.startTime = time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	pow2, _ := ParseHistogram("size")
+	bounds, _ := ParseHistogram("size:10,100")
+	weeks, _ := ParseHistogram("age:week")
+	var tests = []struct {
+		hist  *Histogram
+		entry fileEntry
+		key   int
+		label string
+	}{
+		{pow2, fileEntry{ColSize: int64(0)}, 0, "0"},
+		{pow2, fileEntry{ColSize: int64(1)}, 1, "1-2"},
+		{pow2, fileEntry{ColSize: int64(3000)}, 12, "2Ki-4Ki"},
+		{pow2, fileEntry{}, histNullKey, `\~`},
+		{bounds, fileEntry{ColSize: int64(9)}, 0, "0-10"},
+		{bounds, fileEntry{ColSize: int64(10)}, 1, "10-100"},
+		{bounds, fileEntry{ColSize: int64(100)}, 2, "100+"},
+		{weeks, fileEntry{ColMtime: "2017-02-25T00:00:00Z"}, 0, "0 weeks"},
+		{weeks, fileEntry{ColMtime: "2017-02-20T00:00:00Z"}, 1, "1 week"},
+		{weeks, fileEntry{ColMtime: "2017-01-01T00:00:00Z"}, 8, "8 weeks"},
+		{weeks, fileEntry{ColMtime: "2018-01-01T00:00:00Z"}, 0, "0 weeks"},
+		{weeks, fileEntry{ColMtime: "bad"}, histNullKey, `\~`},
+	}
+	for _, test := range tests {
+		key := test.hist.bucket(ctx, test.entry)
+		checkVal(t, test.key, key)
+		checkVal(t, test.label, test.hist.label(key))
+	}
+}
+
+func Test_Context_calcHistogramInfo(t *testing.T) {
+	ctx := NewContext()
+	ctx.Roots[0] = []string{"a"}
+	ctx.Roots[1] = []string{"b"}
+	ctx.Histogram, _ = ParseHistogram("size")
+	ctx.HistogramBars = 4
+	for _, e := range []fileEntry{
+		{ColPath: "a", ColSize: int64(1), ColSide: int64(0)},
+		{ColPath: "b", ColSize: int64(6), ColSide: int64(0)},
+		{ColPath: "c", ColSize: int64(7), ColSide: int64(1)},
+		{ColPath: "d/", ColSize: int64(100), ColSide: int64(1)},
+	} {
+		ctx.updateOutputStats(e)
+	}
+	checkVal(t, [][]string{
+		{"HISTOGRAM:", "L:Count", "L:Size", "    ", "R:Count", "R:Size", ""},
+		{"       1-2", "      1", "     1", "#   ", "      0", "     0", ""},
+		{"       2-4", "      0", "     0", "    ", "      0", "     0", ""},
+		{"       4-8", "      1", "     6", "####", "      1", "     7", "####"},
+	}, ctx.calcHistogramInfo())
+	c, s := ctx.outputStats.get(1)
+	checkVal(t, []int64{2, 7}, []int64{c, s})
+}
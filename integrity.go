@@ -0,0 +1,233 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Values of the integrity column
+const (
+	integrityOk          = "ok"
+	integrityCorrupt     = "corrupt"
+	integrityUnsupported = "unsupported"
+)
+
+// Magic numbers of the self-checking formats
+const (
+	zipMagic      = "PK\x03\x04"
+	zipEmptyMagic = "PK\x05\x06"
+	gzipMagic     = "\x1f\x8b"
+	bzip2Magic    = "BZh"
+	bzip2Block    = "1AY&SY"       // first block, after the level digit
+	bzip2EndBlock = "\x17rE8P\x90" // end of stream, for empty data
+	pngMagic      = "\x89PNG\r\n\x1a\n"
+	jpegMagic     = "\xff\xd8\xff"
+	tarMagic      = "ustar"
+	tarMagicPos   = 257
+)
+
+// Return true if the data starts with a bzip2 stream header: the magic
+// number, a block size level and the magic number of a block.
+func isBzip2(head []byte) bool {
+	n := len(bzip2Magic)
+	if !bytes.HasPrefix(head, []byte(bzip2Magic)) || len(head) < n+1+len(bzip2Block) ||
+		head[n] < '1' || head[n] > '9' {
+		return false
+	}
+	block := string(head[n+1 : n+1+len(bzip2Block)])
+	return block == bzip2Block || block == bzip2EndBlock
+}
+
+// Read all the data from a reader, discarding it. Used to make decompressors
+// check their trailers.
+func drain(r io.Reader) error {
+	_, err := io.Copy(ioutil.Discard, r)
+	return err
+}
+
+// Check the CRC of every entry in a zip archive.
+func checkZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = drain(rc)
+		rc.Close()
+		if err != nil {
+			return errors.New(f.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Check the CRC and length trailers of all members of a gzip file.
+func checkGzip(r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	return drain(zr)
+}
+
+// Check the headers of every entry in a tar file, which have their own
+// checksums, and that no entry data is truncated.
+func checkTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = drain(tr); err != nil {
+			return err
+		}
+	}
+}
+
+// Check the CRC of each chunk of a PNG file, up to the IEND chunk.
+func checkPng(r io.Reader) error {
+	if _, err := io.CopyN(ioutil.Discard, r, int64(len(pngMagic))); err != nil {
+		return err
+	}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return errors.New("truncated before IEND chunk")
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		chunkType := string(header[4:])
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		if _, err := io.CopyN(crc, r, int64(length)); err != nil {
+			return errors.New("truncated " + chunkType + " chunk")
+		}
+		if _, err := io.ReadFull(r, header[:4]); err != nil {
+			return errors.New("truncated " + chunkType + " chunk")
+		}
+		if binary.BigEndian.Uint32(header[:4]) != crc.Sum32() {
+			return errors.New("bad CRC in " + chunkType + " chunk")
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+	}
+}
+
+// Check that a JPEG file ends with an end of image marker. JPEG has no
+// checksums, but this catches the common case of truncated files. Some
+// writers pad the end of the file with zeros, so those are skipped.
+func checkJpeg(r io.ReaderAt, size int64) error {
+	tailSize := int64(4096)
+	if tailSize > size {
+		tailSize = size
+	}
+	tail := make([]byte, tailSize)
+	if _, err := r.ReadAt(tail, size-tailSize); err != nil {
+		return err
+	}
+	tail = bytes.TrimRight(tail, "\x00")
+	if !bytes.HasSuffix(tail, []byte("\xff\xd9")) {
+		return errors.New("missing end of image marker")
+	}
+	return nil
+}
+
+// Detect the format of a file and validate it using its internal checksums
+// or structure. Returns false if the format isn't one that can be checked.
+// Otherwise, returns an error describing the problem if the file is corrupt.
+func checkIntegrity(r io.ReaderAt, size int64) (bool, error) {
+	head := make([]byte, tarMagicPos+len(tarMagic))
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+	stream := io.NewSectionReader(r, 0, size)
+	switch {
+	case bytes.HasPrefix(head, []byte(zipMagic)), bytes.HasPrefix(head, []byte(zipEmptyMagic)):
+		return true, checkZip(r, size)
+	case bytes.HasPrefix(head, []byte(gzipMagic)):
+		return true, checkGzip(stream)
+	case isBzip2(head):
+		return true, drain(bzip2.NewReader(stream))
+	case bytes.HasPrefix(head, []byte(pngMagic)):
+		return true, checkPng(stream)
+	case bytes.HasPrefix(head, []byte(jpegMagic)):
+		return true, checkJpeg(r, size)
+	case len(head) == tarMagicPos+len(tarMagic) && string(head[tarMagicPos:]) == tarMagic:
+		return true, checkTar(stream)
+	}
+	return false, nil
+}
+
+// Compute the integrity column for a file by reading it. Nonregular files get
+// an empty value, like the digest columns. The reason for any corruption
+// found is reported as an error so it appears in the summary.
+func (self *Context) calcIntegrityFile(root string, entry fileEntry) {
+	// get the file name and open it
+	relPath, ok := entry.getStringField(ColPath)
+	if !ok {
+		self.onError("Missing path in file entry: ")
+		return
+	}
+	filePath := myJoin(root, relPath)
+	fi, err := self.statFile(filePath)
+	if err != nil {
+		self.onError("Can't get file information: ", err)
+		return
+	}
+	if !fi.Mode().IsRegular() {
+		entry.setStringField(ColIntegrity, "")
+		return
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		self.onError("Can't open file for reading: ", err)
+		return
+	}
+	defer file.Close()
+
+	self.outTempf(0, "integrity %s", filePath)
+	supported, err := checkIntegrity(file, fi.Size())
+	switch {
+	case !supported:
+		entry.setStringField(ColIntegrity, integrityUnsupported)
+	case err != nil:
+		self.onError("Corrupt file: ", filePath, ": ", err)
+		entry.setStringField(ColIntegrity, integrityCorrupt)
+	default:
+		entry.setStringField(ColIntegrity, integrityOk)
+	}
+}
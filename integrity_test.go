@@ -0,0 +1,150 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Return a copy of data with one byte inverted
+func flipByte(data []byte, pos int) []byte {
+	out := append([]byte{}, data...)
+	out[pos] ^= 0xff
+	return out
+}
+
+func Test_checkIntegrity(t *testing.T) {
+	content := []byte("the quick brown fox jumps over the lazy dog")
+
+	zipBuf := bytes.Buffer{}
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.CreateHeader(&zip.FileHeader{Name: "fox.txt", Method: zip.Store})
+	w.Write(content)
+	zw.Close()
+	zipData := zipBuf.Bytes()
+
+	emptyZipBuf := bytes.Buffer{}
+	zip.NewWriter(&emptyZipBuf).Close()
+
+	gzBuf := bytes.Buffer{}
+	gw := gzip.NewWriter(&gzBuf)
+	gw.Write(content)
+	gw.Close()
+	gzData := gzBuf.Bytes()
+
+	// "hello" compressed by bzip2
+	bz2Data := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x19, 0x31,
+		0x65, 0x3d, 0x00, 0x00, 0x00, 0x81, 0x00, 0x02, 0x44, 0xa0, 0x00, 0x21,
+		0x9a, 0x68, 0x33, 0x4d, 0x07, 0x33, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48,
+		0x0c, 0x98, 0xb2, 0x9e, 0x80}
+
+	tarBuf := bytes.Buffer{}
+	tw := tar.NewWriter(&tarBuf)
+	tw.WriteHeader(&tar.Header{Name: "fox.txt", Mode: 0644, Size: int64(len(content))})
+	tw.Write(content)
+	tw.Close()
+	tarData := tarBuf.Bytes()
+
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	pngBuf := bytes.Buffer{}
+	png.Encode(&pngBuf, img)
+	pngData := pngBuf.Bytes()
+	jpegBuf := bytes.Buffer{}
+	jpeg.Encode(&jpegBuf, img, nil)
+	jpegData := jpegBuf.Bytes()
+
+	var tests = []struct {
+		data          []byte
+		wantSupported bool
+		wantErr       string
+	}{
+		{[]byte("plain text"), false, ""},
+		{nil, false, ""},
+		{zipData, true, ""},
+		{emptyZipBuf.Bytes(), true, ""},
+		{flipByte(zipData, 40), true, "fox.txt: zip: checksum error"},
+		{gzData, true, ""},
+		{flipByte(gzData, len(gzData)-6), true, "gzip: invalid checksum"},
+		{gzData[:len(gzData)-4], true, "unexpected EOF"},
+		{bz2Data, true, ""},
+		{flipByte(bz2Data, 11), true, "bzip2 data invalid: block checksum mismatch"},
+		{[]byte("BZhang notes"), false, ""},
+		{[]byte("BZh91AY"), false, ""},
+		{[]byte("BZh9\x17rE8P\x90\x00\x00\x00\x00"), true, ""},
+		{tarData, true, ""},
+		{flipByte(tarData, 0), true, "archive/tar: invalid tar header"},
+		{tarData[:530], true, "unexpected EOF"},
+		{pngData, true, ""},
+		{flipByte(pngData, len(pngData)-20), true, "bad CRC in IDAT chunk"},
+		{pngData[:len(pngData)-12], true, "truncated before IEND chunk"},
+		{jpegData, true, ""},
+		{append(append([]byte{}, jpegData...), 0, 0), true, ""},
+		{jpegData[:len(jpegData)-10], true, "missing end of image marker"},
+	}
+	for _, test := range tests {
+		supported, err := checkIntegrity(bytes.NewReader(test.data), int64(len(test.data)))
+		checkValErr1(t, test.wantSupported, supported, test.wantErr, err)
+	}
+}
+
+func Test_Context_calcIntegrityFile(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	gzBuf := bytes.Buffer{}
+	gw := gzip.NewWriter(&gzBuf)
+	gw.Write([]byte("foo"))
+	gw.Close()
+	gzData := gzBuf.Bytes()
+	ioutil.WriteFile(filepath.Join(dirPath, "good.gz"), gzData, 0644)
+	ioutil.WriteFile(filepath.Join(dirPath, "bad.gz"), gzData[:len(gzData)-1], 0644)
+	ioutil.WriteFile(filepath.Join(dirPath, "text"), []byte("foo"), 0644)
+
+	ctx := NewContext()
+	var tests = []struct {
+		path string
+		want string
+	}{
+		{"good.gz", integrityOk},
+		{"bad.gz", integrityCorrupt},
+		{"text", integrityUnsupported},
+		{".", ""},
+	}
+	for _, test := range tests {
+		entry := fileEntry{ColPath: test.path}
+		ctx.calcIntegrityFile(dirPath, entry)
+		got, _ := entry.getStringField(ColIntegrity)
+		checkVal(t, test.want, got)
+	}
+	checkVal(t, 1, ctx.errorCount)
+}
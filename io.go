@@ -38,6 +38,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Magic header identifying file sifter files
@@ -45,6 +46,11 @@ const sifterFileHeader = "| File Sifter output file - V1 |"
 
 // Parse a sifter file and load its entries into the current context.
 func (self *Context) loadSifterFile(r io.Reader) error {
+	return self.scanSifterFile(r, func(entry fileEntry) { self.indexEntry(entry) })
+}
+
+// Parse a sifter file, calling the given handler for each entry.
+func (self *Context) scanSifterFile(r io.Reader, handler func(fileEntry)) error {
 	columns := []Column{}          // columns detected in the file from header directive
 	scanner := bufio.NewScanner(r) // help read file by lines
 	var err error
@@ -92,7 +98,7 @@ func (self *Context) loadSifterFile(r io.Reader) error {
 			}
 			// get field value and add it to the file entry
 			field, notNull := unescapeField(string(line[:end]))
-			if notNull {
+			if notNull && columns[i] != colAggregate {
 				err = entry.parseAndSetField(self, columns[i], field)
 				if err != nil {
 					self.onError("Parse error in FSIFT file: ", err)
@@ -103,30 +109,47 @@ func (self *Context) loadSifterFile(r io.Reader) error {
 		}
 
 		if err == nil {
-			// add "side" field if needed
-			if self.needsCol(ColSide) {
-				entry.setBoolField(ColSide, self.CurSide)
-			}
-			// check any prefilter conditions against the entry
-			match, notNull := self.preFilter.filter(entry)
-			self.checkNullCompare(notNull)
-			// get size field for stats computation; directory sizes assumed zero for stats
-			size := entry.getNumericFieldOrZero(ColSize)
-			path, _ := entry.getStringField(ColPath)
-			if strings.HasSuffix(path, "/") {
-				size = 0
-			}
-			self.scanStats.update(self.CurSide, size)
-			// if prefilter passes, add the entry to the current context
-			if match {
-				self.indexStats.update(self.CurSide, size)
-				self.entries = append(self.entries, entry)
-			}
+			handler(entry)
 		}
 	}
 	return scanner.Err()
 }
 
+// Add an entry that was not found by the file system scanner (for example,
+// one loaded from a FSIFT file) to the current context. The side field is set
+// if needed, the stats are updated, and the entry is only added if it passes
+// the prefilter. Returns true if the entry was added.
+func (self *Context) indexEntry(entry fileEntry) bool {
+	// add "side" field if needed
+	if self.needsCol(ColSide) {
+		entry.setNumericField(ColSide, int64(self.CurSide))
+	}
+	// add root fields if needed, keeping any loaded with the entry
+	self.setRootColumns(entry)
+	// compute age from the loaded modification time if needed
+	if self.needsCol(ColAge) {
+		if mstamp, ok := entry.getNumericField(ColMstamp); ok {
+			entry.setNumericField(ColAge, self.ageOf(time.Unix(mstamp, 0)))
+		}
+	}
+	// check any prefilter conditions against the entry
+	match, notNull := self.preFilter.filter(entry)
+	self.checkNullCompare(notNull)
+	// get size field for stats computation; directory sizes assumed zero for stats
+	size := entry.getNumericFieldOrZero(ColSize)
+	path, _ := entry.getStringField(ColPath)
+	if strings.HasSuffix(path, "/") {
+		size = 0
+	}
+	self.scanStats.update(self.CurSide, size)
+	// if prefilter passes, add the entry to the current context
+	if match {
+		self.updateIndexStats(size)
+		self.entries = append(self.entries, entry)
+	}
+	return match
+}
+
 // Compute a string representation of the given number using the current format settings in the context.
 func (self *Context) formatNumber(n int64) string {
 	// compute the basic decimal number, abs value and sign
@@ -221,8 +244,18 @@ func (self *Context) processFile(root, relPath string, pruneCheck bool) (fileEnt
 			entry.setStringField(col, timeToMtime(finfo.ModTime(), nil)) // always UTC
 		case ColMstamp:
 			entry.setNumericField(col, finfo.ModTime().Unix())
+		case ColAge:
+			entry.setNumericField(col, self.ageOf(finfo.ModTime()))
 		case ColSide:
-			entry.setBoolField(col, self.CurSide)
+			entry.setNumericField(col, int64(self.CurSide))
+		case ColRoot:
+			entry.setStringField(col, self.curRoot)
+		case ColRootIdx:
+			entry.setNumericField(col, int64(len(self.rootStats)))
+		case ColAbsPath:
+			if self.curRootAbs != "" {
+				entry.setStringField(col, self.absPath(relPath))
+			}
 		case ColDevice:
 			entry.setNumericField(col, int64(xinfo.device))
 		case ColNlinks:
@@ -264,14 +297,13 @@ func (self *Context) processFile(root, relPath string, pruneCheck bool) (fileEnt
 	if !pruneCheck {
 		// update the "scan" stats and the interactive progress message
 		self.scanStats.update(self.CurSide, size)
-		allBytes := self.scanStats.leftSize + self.scanStats.rightSize
-		allFiles := self.scanStats.leftCount + self.scanStats.rightCount
+		allFiles, allBytes := self.scanStats.total()
 		self.outTempf(0, "Scan(%dMB in %d) %s", allBytes/1000000, allFiles, filePath)
 
 		// apply any prefilters; if not filtered, update index stats and add entry to context
 		match, notNull = self.preFilter.filter(entry)
 		if match {
-			self.indexStats.update(self.CurSide, size)
+			self.updateIndexStats(size)
 			self.entries = append(self.entries, entry)
 		}
 	} else {
@@ -382,18 +414,23 @@ func (self *Context) statFile(path string) (os.FileInfo, error) {
 	}
 }
 
+// The digest columns, in the order they are calculated
+var digestColumns = []Column{ColMd5, ColSha1, ColSha256, ColSha512, ColCrc32, ColGitBlob}
+
 // Map from digest column IDs to algorithm factories
 var hashes = map[Column]func() hash.Hash{
-	ColMd5:    md5.New,
-	ColSha256: sha256.New,
-	ColSha512: sha512.New,
-	ColSha1:   sha1.New,
-	ColCrc32:  func() hash.Hash { return crc32.NewIEEE() },
+	ColMd5:     md5.New,
+	ColSha256:  sha256.New,
+	ColSha512:  sha512.New,
+	ColSha1:    sha1.New,
+	ColCrc32:   func() hash.Hash { return crc32.NewIEEE() },
+	ColGitBlob: sha1.New,
 }
 
-// Compute the value of a digest field for a file by reading the file.
-// col specifies the type of digest. The field is added to the given entry.
-func (self *Context) calcDigestFile(col Column, root string, entry fileEntry) {
+// Compute the values of all needed digest fields (and the entropy field) for
+// a file in a single pass of reading the file. The fields are added to the
+// given entry.
+func (self *Context) calcDigestFile(root string, entry fileEntry) {
 	// get the file name and open it
 	relPath, ok := entry.getStringField(ColPath)
 	if !ok {
@@ -408,7 +445,14 @@ func (self *Context) calcDigestFile(col Column, root string, entry fileEntry) {
 	}
 	if !fi.Mode().IsRegular() {
 		// nonregular files get empty digests (not null, so we don't get null compare warnings)
-		entry.setStringField(col, "")
+		for _, col := range digestColumns {
+			if self.needsCol(col) {
+				entry.setStringField(col, "")
+			}
+		}
+		if self.needsCol(ColEntropy) {
+			entry.setStringField(ColEntropy, "")
+		}
 		return
 	}
 	file, err := os.Open(filePath)
@@ -418,36 +462,87 @@ func (self *Context) calcDigestFile(col Column, root string, entry fileEntry) {
 	}
 	defer file.Close()
 
-	// create the hash algorithm and feed the file data to it, then add the result to the entry
+	// create the hash algorithms and feed the file data to all of them at once
 	// TODO: for huge files, read in chunks, update info message periodically
-	hashFactory, _ := hashes[col]
-	hash := hashFactory()
-	_, err = io.Copy(hash, file)
+	sums := map[Column]hash.Hash{}
+	writers := []io.Writer{}
+	for _, col := range digestColumns {
+		if !self.needsCol(col) {
+			continue
+		}
+		hash := hashes[col]()
+		if col == ColGitBlob {
+			// git object IDs include a header with the object type and size
+			fmt.Fprintf(hash, "blob %d\x00", fi.Size())
+		}
+		sums[col] = hash
+		writers = append(writers, hash)
+	}
+	var reader io.Reader = file
+	counter := entropyCounter{limit: self.EntropySample}
+	if self.needsCol(ColEntropy) {
+		writers = append(writers, &counter)
+		if len(sums) == 0 && counter.limit > 0 {
+			// only sampling for entropy; no need to read the whole file
+			reader = io.LimitReader(file, counter.limit)
+		}
+	}
+	_, err = io.Copy(io.MultiWriter(writers...), reader)
 	if err != nil {
 		self.onError("Can't read file for digest calculation: ", err)
 		return
 	}
-	sum := hex.EncodeToString(hash.Sum(nil))
-	entry.setStringField(col, sum)
+
+	// add the results to the entry
+	for col, hash := range sums {
+		entry.setStringField(col, hex.EncodeToString(hash.Sum(nil)))
+	}
+	if self.needsCol(ColEntropy) {
+		entry.setStringField(ColEntropy, formatEntropy(counter.entropy()))
+	}
 
 	// update the interactive info message with the scan progress
 	self.curFileCount++
 	self.curByteCount += entry.getNumericFieldOrZero(ColSize)
-	// TODO: if multiple digest cols specified, displayed counts will be off
-	allBytes := self.scanStats.leftSize + self.scanStats.rightSize
-	allFiles := self.scanStats.leftCount + self.scanStats.rightCount
-	self.outTempf(0, "%s(%dMB/%dMB in %d/%d) %s", col,
+	allFiles, allBytes := self.scanStats.total()
+	self.outTempf(0, "Reading (%dMB/%dMB in %d/%d) %s",
 		self.curByteCount/1000000, allBytes/1000000,
 		self.curFileCount, allFiles, filePath)
 }
 
+// Return true if any of the columns computed by reading whole files in
+// calcDigestFile are needed for this run.
+func (self *Context) needsDigestCols() bool {
+	for _, col := range digestColumns {
+		if self.needsCol(col) {
+			return true
+		}
+	}
+	return self.needsCol(ColEntropy)
+}
+
 // Calculate any needed digest fields for the file entries in the given list.
+// Also calculate any needed fields that come from parsing file headers or
+// validating file contents.
 func (self *Context) calcDigestList(root string, entries []fileEntry) {
-	for _, col := range []Column{ColMd5, ColSha1, ColSha256, ColSha512, ColCrc32} {
-		if self.neededCols[col] {
-			for _, entry := range entries {
-				self.calcDigestFile(col, root, entry)
-			}
+	if self.needsDigestCols() {
+		for _, entry := range entries {
+			self.calcDigestFile(root, entry)
+		}
+	}
+	if self.needsElfCols() {
+		for _, entry := range entries {
+			self.calcElfFile(root, entry)
+		}
+	}
+	if self.needsCol(ColGrepCount) || self.needsCol(ColGrepLine) {
+		for _, entry := range entries {
+			self.calcGrepFile(root, entry)
+		}
+	}
+	if self.neededCols[ColIntegrity] {
+		for _, entry := range entries {
+			self.calcIntegrityFile(root, entry)
 		}
 	}
 }
@@ -455,6 +550,7 @@ func (self *Context) calcDigestList(root string, entries []fileEntry) {
 // Scan a given "root" specified on the command line, adding entries
 // to the context as appropriate.
 func (self *Context) processRoot(path string) {
+	self.startRoot(path)
 	if path == "-" {
 		// special case: '-' means stdin
 		err := self.loadSifterFile(os.Stdin)
@@ -465,6 +561,14 @@ func (self *Context) processRoot(path string) {
 	}
 	finfo, err := self.statFile(path)
 	if err != nil {
+		// a nonexistent path may name a tree in a git repository
+		if repoDir, ref, ok := splitGitRoot(path); ok {
+			err = self.processGitRoot(repoDir, ref)
+			if err != nil {
+				self.fatal("Can't load git tree:", err)
+			}
+			return
+		}
 		self.fatal("Can't get file information:", err)
 	}
 	if !finfo.IsDir() {
@@ -475,6 +579,9 @@ func (self *Context) processRoot(path string) {
 		}
 		if isFS {
 			// it's a FSIFT file; parse it and load its entries
+			if self.Grep != nil {
+				self.fatal("--grep can't search the files of a FSIFT file root: ", path)
+			}
 			f, err := os.Open(path)
 			if err != nil {
 				self.fatal("Can't open file:", err)
@@ -485,12 +592,14 @@ func (self *Context) processRoot(path string) {
 			}
 		} else {
 			// not a FSIFT file; just add an entry for it
+			self.curRootAbs, _ = filepath.Abs(path)
 			base := len(self.entries)
 			self.processFile(path, "", false)
-			self.calcDigestList("", self.entries[base:])
+			self.calcDigestList(path, self.entries[base:])
 		}
 	} else {
 		// root is a directory; go scan it
+		self.curRootAbs, _ = filepath.Abs(path)
 		base := len(self.entries)
 		self.scanDirTree(path, ".", []os.FileInfo{finfo})
 		// calc any digests for the newly added entries
@@ -503,6 +612,38 @@ func (self *Context) headerOut(format string, a ...interface{}) {
 	self.outf(-1, "| "+format, a...)
 }
 
+// Output lines of fields, padding all but the last field of each line to the
+// max width of its column, on the left for numeric columns. In plain0 mode,
+// fields aren't padded.
+func (self *Context) outputTable(lines [][]string, numeric []bool, indent, separator string) {
+	self.padTable(lines, numeric)
+	for _, fields := range lines {
+		self.outf(-1, indent+"%s", strings.Join(fields, separator))
+	}
+}
+
+// Pad all but the last field of each line of fields in place, like
+// outputTable.
+func (self *Context) padTable(lines [][]string, numeric []bool) {
+	widths := make([]int, len(numeric))
+	for _, fields := range lines {
+		for j := 0; j < len(fields)-1 && !self.Plain0; j++ {
+			if width := utf8.RuneCountInString(fields[j]); width > widths[j] {
+				widths[j] = width
+			}
+		}
+	}
+	for _, fields := range lines {
+		for j := 0; j < len(fields)-1 && !self.Plain0; j++ {
+			if numeric[j] {
+				fields[j] = fmt.Sprintf("%*s", widths[j], fields[j])
+			} else {
+				fields[j] = fmt.Sprintf("%-*s", widths[j], fields[j])
+			}
+		}
+	}
+}
+
 // Output the header info before processing roots
 func (self *Context) showHeader() {
 	if self.Plain {
@@ -514,8 +655,11 @@ func (self *Context) showHeader() {
 		self.outf(-1, "[")
 		return
 	}
-	// output magic header ID and command line parameters
-	self.outf(-1, "%s", sifterFileHeader)
+	// output magic header ID and command line parameters; side-by-side output
+	// can't be loaded as a FSIFT file, so it has none
+	if !self.sideBySide() {
+		self.outf(-1, "%s", sifterFileHeader)
+	}
 	cmdLine := strings.Join(os.Args[1:], " ")
 	if len(cmdLine) > 500 {
 		cmdLine = cmdLine[:500] + " ..."
@@ -525,6 +669,9 @@ func (self *Context) showHeader() {
 	cwd, _ := os.Getwd()
 	self.headerOut("Current working directory: %s", cwd)
 	self.headerOut("Compare keys: %s", formatColumnNames(self.KeyCols.cols))
+	if self.usesIdentity() {
+		self.headerOut("Identity keys: %s", formatColumnNames(self.IdentityCols.cols))
+	}
 	if len(self.SortCols.cols) > 0 {
 		self.headerOut("Sort keys: %s", formatColumnNames(self.SortCols.cols))
 	}
@@ -536,10 +683,15 @@ func (self *Context) showHeader() {
 	}
 	self.headerOut("Evaluated columns: %s", formatColumnNames(needed))
 	// output start time and the main entry column header
-	self.startTime = time.Now()
 	self.headerOut("Run start time: %v", timeToMtime(self.startTime, self.OutputTimezone))
 	self.headerOut("")
-	self.headerOut("Columns: %s", formatColumnNames(self.OutCols.cols))
+	if self.grouping() {
+		self.headerOut("Columns: %s", strings.Join(self.groupColumnNames(), ","))
+	} else if self.sideBySide() {
+		self.headerOut("Side-by-side columns: %s", self.sideBySideColumnNames())
+	} else {
+		self.headerOut("Columns: %s", formatColumnNames(self.OutCols.cols))
+	}
 	self.headerOut("")
 }
 
@@ -581,6 +733,20 @@ func (self *Context) showFooter() {
 	for _, line := range self.calcSummaryInfo() {
 		self.headerOut(strings.Join(line, "  "))
 	}
+	// if a side has several roots, also show the files indexed from each one
+	if len(self.rootStats) > len(self.Roots) {
+		self.headerOut("")
+		for _, line := range self.calcRootInfo() {
+			self.headerOut(strings.Join(line, "  "))
+		}
+	}
+	// show the files output in each histogram bucket
+	if self.Histogram != nil {
+		self.headerOut("")
+		for _, line := range self.calcHistogramInfo() {
+			self.headerOut(strings.TrimRight(strings.Join(line, "  "), " "))
+		}
+	}
 
 	// show any warnings or errors
 	self.showErrors(self.warningMessages, self.warningCount, "WARNINGS")
@@ -65,6 +65,16 @@ func Test_Context_loadSifterFile(t *testing.T) {
 			},
 			"",
 			[]string{`Error: Parse error in FSIFT file: strconv.ParseInt: parsing "bad": invalid syntax`}},
+		{
+			// grouped output loads the group fields, skipping the aggregates
+			`| Columns: ext,user,count,sum:size
+  .go  joe  3  7
+  \-   sue  1  10`, false,
+			[]fileEntry{
+				{ColExt: ".go", ColUser: "joe"},
+				{ColExt: "", ColUser: "sue"},
+			},
+			"", nil},
 	}
 	for _, test := range tests {
 		ctx := NewContext()
@@ -157,7 +167,7 @@ func Test_myJoin(t *testing.T) {
 
 func Test_Context_processFile(t *testing.T) {
 	ctx := NewContext()
-	ctx.CurSide = true
+	ctx.CurSide = 1
 	// nonexistent file
 	got, gotSize := ctx.processFile("", "noexist-9161ffff-b5ed-41f8-8205-5aa6f9e6e05a", false)
 	checkVal(t, fileEntry(nil), got)
@@ -242,10 +252,10 @@ func Test_Context_processFile(t *testing.T) {
 	checkVal(t, int64(0), gotSize)
 
 	// check final stats
-	checkVal(t, int64(3), ctx.scanStats.rightCount)
-	checkVal(t, int64(6), ctx.scanStats.rightSize)
-	checkVal(t, int64(2), ctx.indexStats.rightCount)
-	checkVal(t, int64(3), ctx.indexStats.rightSize)
+	checkVal(t, int64(3), ctx.scanStats.counts[1])
+	checkVal(t, int64(6), ctx.scanStats.sizes[1])
+	checkVal(t, int64(2), ctx.indexStats.counts[1])
+	checkVal(t, int64(3), ctx.indexStats.sizes[1])
 }
 
 func Test_Context_calcDigestList(t *testing.T) {
@@ -293,3 +303,22 @@ func Test_Context_calcDigestList(t *testing.T) {
 	}
 	checkVal(t, wantF1, ef1)
 }
+
+func Test_Context_processRoot_fileDigest(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	ioutil.WriteFile(filepath.Join(dirPath, "a"), []byte("foo"), 0644)
+
+	// the digests of a single file root are of that file, not of a path
+	// relative to the current directory
+	ctx := NewContext()
+	ctx.neededCols[ColMd5] = true
+	ctx.processRoot(filepath.Join(dirPath, "a"))
+	checkVal(t, 1, len(ctx.entries))
+	checkVal(t, "acbd18db4cc2f85cedef654fccc4a4d8", ctx.entries[0][ColMd5])
+	checkVal(t, 0, ctx.errorCount)
+}
@@ -0,0 +1,164 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PathMap is a rule that rewrites paths on one side before comparing them.
+type PathMap struct {
+	Pattern     *regexp.Regexp // the pattern to replace
+	Replacement string         // the replacement, which may refer to groups like $1
+}
+
+// ParsePathMap parses a path rewrite rule of the form "REGEX=>REPLACEMENT".
+func ParsePathMap(arg string) (PathMap, error) {
+	parts := strings.SplitN(arg, "=>", 2)
+	if len(parts) != 2 {
+		return PathMap{}, fmt.Errorf("Bad path map (expected REGEX=>REPLACEMENT): '%s'", arg)
+	}
+	rex, err := regexp.Compile(parts[0])
+	return PathMap{Pattern: rex, Replacement: parts[1]}, err
+}
+
+// IdMap maps owner ids (uids, gids, user or group names) on the left side to
+// the equivalent ids on the right side.
+type IdMap map[string]string
+
+// Columns that identify owners, which can be mapped with ParseIdMap
+var idMapColumns = map[Column]bool{ColUid: true, ColGid: true, ColUser: true, ColGroup: true}
+
+// Columns derived from the path; these must be derived again from a
+// rewritten path
+var pathDerivedColumns = []Column{ColBase, ColExt, ColDir, ColDepth}
+
+// ParseIdMap parses an owner mapping of the form "COLUMN:LEFT=RIGHT,..."
+// where COLUMN is uid, gid, user or group, or "COLUMN:@FILE" where FILE
+// holds one LEFT=RIGHT pair per line. Returns the column and the map from
+// left side values to right side values.
+func ParseIdMap(arg string) (Column, IdMap, error) {
+	parts := strings.SplitN(arg, ":", 2)
+	col, ok := colIndex[parts[0]]
+	if len(parts) != 2 || !ok || !idMapColumns[col] {
+		return 0, nil, fmt.Errorf("Bad id map (expected uid, gid, user or group:LEFT=RIGHT,...): '%s'", arg)
+	}
+	var pairs []string
+	if strings.HasPrefix(parts[1], "@") {
+		data, err := ioutil.ReadFile(parts[1][1:])
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, line := range splitSetFile(data) {
+			if s := strings.TrimSpace(string(line)); s != "" && !strings.HasPrefix(s, "#") {
+				pairs = append(pairs, s)
+			}
+		}
+	} else {
+		pairs = strings.Split(parts[1], ",")
+	}
+	idMap := IdMap{}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return 0, nil, fmt.Errorf("Bad id map entry (expected LEFT=RIGHT): '%s'", pair)
+		}
+		if col.isNumeric() {
+			for _, id := range kv {
+				if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+					return 0, nil, err
+				}
+			}
+		}
+		idMap[kv[0]] = kv[1]
+	}
+	return col, idMap, nil
+}
+
+// Return true if any path or id maps are set.
+func (self *Context) hasKeyMaps() bool {
+	return len(self.LeftPathMaps) > 0 || len(self.RightPathMaps) > 0 || len(self.IdMaps) > 0
+}
+
+// Apply path rewrite rules in order to a path.
+func mapPath(p string, maps []PathMap) string {
+	for _, m := range maps {
+		p = m.Pattern.ReplaceAllString(p, m.Replacement)
+	}
+	return p
+}
+
+// Create the entries to compare in place of the given entries when path or
+// id maps are set, or file name key columns are folded, so the entries
+// themselves keep their original values for output. Returns nil if there are
+// no maps and no folding.
+func (self *Context) mapKeyEntries(entries []fileEntry) []fileEntry {
+	if !self.hasKeyMaps() && !self.FoldKeys {
+		return nil
+	}
+	keys := make([]fileEntry, len(entries))
+	for i, entry := range entries {
+		side := sideIndex(entry)
+		maps := self.LeftPathMaps
+		if side > 0 {
+			maps = self.RightPathMaps
+		}
+		key := make(fileEntry, len(entry))
+		for col, val := range entry {
+			key[col] = val
+		}
+		if p, ok := entry.getStringField(ColPath); ok && len(maps) > 0 {
+			key[ColPath] = mapPath(p, maps)
+			for _, col := range pathDerivedColumns {
+				delete(key, col)
+			}
+		}
+		if side == 0 {
+			// id maps translate left side values to right side values
+			for col, idMap := range self.IdMaps {
+				self.mapId(entry, key, col, idMap)
+			}
+		}
+		if self.FoldKeys {
+			foldNameFields(key)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// Set the mapped value of an id column in a key entry, if there is one.
+func (self *Context) mapId(entry, key fileEntry, col Column, idMap IdMap) {
+	if col.isNumeric() {
+		if n, ok := entry.getNumericField(col); ok {
+			if to, ok := idMap[strconv.FormatInt(n, 10)]; ok {
+				n, _ = strconv.ParseInt(to, 10, 64)
+				key.setNumericField(col, n)
+			}
+		}
+	} else if s, ok := entry.getStringField(col); ok {
+		if to, ok := idMap[s]; ok {
+			key.setStringField(col, to)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ParsePathMap(t *testing.T) {
+	var tests = []struct {
+		arg     string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{`^home/(\w+)/=>users/$1/`, "home/alice/a.txt", "users/alice/a.txt", ""},
+		{`\.jpeg$=>.jpg`, "x/a.jpeg", "x/a.jpg", ""},
+		{`a=>b=>c`, "a", "b=>c", ""},
+		{`a`, "", "", "Bad path map"},
+		{`(=>x`, "", "", "error parsing regexp"},
+	}
+	for _, test := range tests {
+		m, err := ParsePathMap(test.arg)
+		if test.wantErr != "" {
+			checkValErr1(t, nil, nil, test.wantErr, err)
+			continue
+		}
+		checkValErr1(t, test.want, mapPath(test.path, []PathMap{m}), "", err)
+	}
+}
+
+func Test_ParseIdMap(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	mapPath := filepath.Join(dirPath, "users.txt")
+	ioutil.WriteFile(mapPath, []byte("# left=right\nalice=alice2\n\n bob=robert \n"), 0644)
+
+	var tests = []struct {
+		arg     string
+		wantCol Column
+		want    IdMap
+		wantErr string
+	}{
+		{"uid:1000=501", ColUid, IdMap{"1000": "501"}, ""},
+		{"gid:100=20,0=0", ColGid, IdMap{"100": "20", "0": "0"}, ""},
+		{"user:@" + mapPath, ColUser, IdMap{"alice": "alice2", "bob": "robert"}, ""},
+		{"group:staff=users", ColGroup, IdMap{"staff": "users"}, ""},
+		{"size:1=2", 0, nil, "Bad id map"},
+		{"uid", 0, nil, "Bad id map"},
+		{"uid:1000", 0, nil, "Bad id map entry"},
+		{"uid:alice=1", 0, nil, "strconv.ParseInt"},
+		{"user:@" + filepath.Join(dirPath, "none"), 0, nil, "open "},
+	}
+	for _, test := range tests {
+		col, idMap, err := ParseIdMap(test.arg)
+		checkValErr1(t, test.want, idMap, test.wantErr, err)
+		checkVal(t, test.wantCol, col)
+	}
+}
+
+func Test_Context_mapKeyEntries(t *testing.T) {
+	ctx := NewContext()
+	entries := []fileEntry{{ColPath: "home/a/x.jpeg", ColBase: "x.jpeg", ColUid: int64(1000), ColUser: "alice"}}
+	checkVal(t, true, ctx.mapKeyEntries(entries) == nil)
+
+	leftMap, _ := ParsePathMap(`^home/=>users/`)
+	extMap, _ := ParsePathMap(`\.jpeg$=>.jpg`)
+	ctx.LeftPathMaps = []PathMap{leftMap, extMap}
+	ctx.IdMaps = map[Column]IdMap{ColUid: {"1000": "501"}, ColUser: {"bob": "robert"}}
+	entries = []fileEntry{
+		{ColPath: "home/a/x.jpeg", ColBase: "x.jpeg", ColUid: int64(1000), ColUser: "alice", ColSide: int64(0)},
+		{ColPath: "home/a/x.jpeg", ColUid: int64(1000), ColSide: int64(1)},
+	}
+	keys := ctx.mapKeyEntries(entries)
+	checkVal(t, fileEntry{ColPath: "users/a/x.jpg", ColUid: int64(501), ColUser: "alice", ColSide: int64(0)}, keys[0])
+	base, _ := keys[0].getStringField(ColBase)
+	checkVal(t, "x.jpg", base)
+	checkVal(t, fileEntry{ColPath: "home/a/x.jpeg", ColUid: int64(1000), ColSide: int64(1)}, keys[1])
+	// originals are unchanged
+	checkVal(t, "home/a/x.jpeg", entries[0][ColPath])
+	checkVal(t, "x.jpeg", entries[0][ColBase])
+	checkVal(t, int64(1000), entries[0][ColUid])
+
+	// file name columns are folded, leaving the originals unchanged
+	ctx = NewContext()
+	ctx.FoldKeys = true
+	entries = []fileEntry{{ColPath: "Home/X.JPEG", ColSize: int64(1)}}
+	keys = ctx.mapKeyEntries(entries)
+	checkVal(t, fileEntry{ColPath: "home/x.jpeg", ColSize: int64(1)}, keys[0])
+	checkVal(t, "Home/X.JPEG", entries[0][ColPath])
+}
+
+func Test_Context_analyzeMatchesKeyMaps(t *testing.T) {
+	ctx := NewContext()
+	ctx.outputState.errStream = ioutil.Discard
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColUser}}
+	leftMap, _ := ParsePathMap(`^home/=>users/`)
+	ctx.LeftPathMaps = []PathMap{leftMap}
+	ctx.IdMaps = map[Column]IdMap{ColUser: {"alice": "alice2"}}
+	ctx.entries = []fileEntry{
+		{ColPath: "home/a", ColUser: "alice", ColSide: int64(0)},
+		{ColPath: "home/b", ColUser: "bob", ColSide: int64(0)},
+		{ColPath: "users/b", ColUser: "bob", ColSide: int64(1)},
+		{ColPath: "users/a", ColUser: "alice2", ColSide: int64(1)},
+		{ColPath: "home/c", ColUser: "bob", ColSide: int64(1)}, // right side isn't mapped
+		{ColPath: "users/c", ColUser: "bob", ColSide: int64(0)},
+	}
+	ctx.analyzeMatches()
+	var got []int64
+	for _, entry := range ctx.entries {
+		got = append(got, entry[ColMatched].(int64))
+	}
+	checkVal(t, []int64{1, 1, 1, 1, 0, 0}, got)
+	checkVal(t, "home/a", ctx.entries[0][ColPath])
+}
@@ -0,0 +1,130 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// A heap of the entries kept for a group by --limit. The entry that sorts
+// last is at the top, so it can be dropped when a better one is added.
+type entryHeap struct {
+	ctx     *Context    // the context the entries belong to
+	entries []fileEntry // the entries kept
+	seqs    []int       // the order each entry was added in, to break ties
+}
+
+// Implement heap.Interface
+func (self *entryHeap) Len() int {
+	return len(self.entries)
+}
+
+func (self *entryHeap) Less(i, j int) bool {
+	return self.ctx.limitOrder(self.entries[j], self.seqs[j], self.entries[i], self.seqs[i])
+}
+
+func (self *entryHeap) Swap(i, j int) {
+	self.entries[i], self.entries[j] = self.entries[j], self.entries[i]
+	self.seqs[i], self.seqs[j] = self.seqs[j], self.seqs[i]
+}
+
+func (self *entryHeap) Push(x interface{}) {
+	item := x.(limitItem)
+	self.entries = append(self.entries, item.entry)
+	self.seqs = append(self.seqs, item.seq)
+}
+
+func (self *entryHeap) Pop() interface{} {
+	n := len(self.entries) - 1
+	item := limitItem{self.entries[n], self.seqs[n]}
+	self.entries, self.seqs = self.entries[:n], self.seqs[:n]
+	return item
+}
+
+// An entry pushed onto an entryHeap, with the order it was added in
+type limitItem struct {
+	entry fileEntry
+	seq   int
+}
+
+// Selects the entries to output for --limit: the first Limit entries in sort
+// order from each group of entries with the same values of the LimitCols.
+// Only the entries kept so far are held, instead of sorting all of them.
+type entryLimiter struct {
+	ctx    *Context              // the context the entries belong to
+	heaps  map[string]*entryHeap // the entries kept for each group
+	groups []*entryHeap          // the heaps in order of their groups' first entries
+	seq    int                   // the number of entries added
+}
+
+// Create an entry limiter for this context's --limit options.
+func (self *Context) newEntryLimiter() *entryLimiter {
+	return &entryLimiter{ctx: self, heaps: map[string]*entryHeap{}}
+}
+
+// Return true if entry e1, added as number seq1, is output before entry e2,
+// added as number seq2: by the sort columns, then in the order added.
+func (self *Context) limitOrder(e1 fileEntry, seq1 int, e2 fileEntry, seq2 int) bool {
+	if len(self.SortCols.cols) > 0 {
+		diff, notNull := e1.compare(e2, self.SortCols.cols)
+		self.checkNullCompare(notNull)
+		if diff != 0 {
+			return diff < 0
+		}
+	}
+	return seq1 < seq2
+}
+
+// Add an entry that passed the postfilter. It's kept if it's among the
+// first Limit entries of its group so far.
+func (self *entryLimiter) add(entry fileEntry) {
+	key := groupKey(entry, self.ctx.LimitCols.cols)
+	h := self.heaps[key]
+	if h == nil {
+		h = &entryHeap{ctx: self.ctx}
+		self.heaps[key] = h
+		self.groups = append(self.groups, h)
+	}
+	seq := self.seq
+	self.seq++
+	if h.Len() < self.ctx.Limit {
+		heap.Push(h, limitItem{entry, seq})
+	} else if self.ctx.limitOrder(entry, seq, h.entries[0], h.seqs[0]) {
+		// replace the kept entry that sorts last
+		h.entries[0], h.seqs[0] = entry, seq
+		heap.Fix(h, 0)
+	}
+}
+
+// Return the entries kept from all groups, in the order they were added.
+func (self *entryLimiter) result() []fileEntry {
+	var items []limitItem
+	for _, h := range self.groups {
+		for i := range h.entries {
+			items = append(items, limitItem{h.entries[i], h.seqs[i]})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+	entries := []fileEntry{}
+	for _, item := range items {
+		entries = append(entries, item.entry)
+	}
+	return entries
+}
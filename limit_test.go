@@ -0,0 +1,63 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_entryLimiter(t *testing.T) {
+	entries := []fileEntry{
+		{ColPath: "a/1", ColDir: "a", ColSize: int64(5)},
+		{ColPath: "b/2", ColDir: "b", ColSize: int64(9)},
+		{ColPath: "a/3", ColDir: "a", ColSize: int64(7)},
+		{ColPath: "a/4", ColDir: "a", ColSize: int64(5)},
+		{ColPath: "b/5", ColDir: "b", ColSize: int64(1)},
+		{ColPath: "a/6", ColDir: "a", ColSize: int64(8)},
+	}
+	var tests = []struct {
+		limit     int
+		sortCols  []Column
+		limitCols []Column
+		want      []string
+	}{
+		{2, nil, nil, []string{"a/1", "b/2"}},
+		{10, nil, nil, []string{"a/1", "b/2", "a/3", "a/4", "b/5", "a/6"}},
+		{3, []Column{ColSize | ColInvertFlag}, nil, []string{"b/2", "a/3", "a/6"}},
+		{2, []Column{ColSize}, nil, []string{"a/1", "b/5"}},
+		{1, []Column{ColSize}, []Column{ColDir}, []string{"a/1", "b/5"}},
+		{2, []Column{ColSize | ColInvertFlag}, []Column{ColDir}, []string{"b/2", "a/3", "b/5", "a/6"}},
+		{1, nil, []Column{ColDir}, []string{"a/1", "b/2"}},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.Limit = test.limit
+		ctx.SortCols.cols = test.sortCols
+		ctx.LimitCols.cols = test.limitCols
+		limiter := ctx.newEntryLimiter()
+		for _, e := range entries {
+			limiter.add(e)
+		}
+		var got []string
+		for _, e := range limiter.result() {
+			got = append(got, entryPath(e))
+		}
+		checkVal(t, test.want, got)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pattern to parse a size with an optional SI (K, M...) or binary (Ki, Mi...)
+// multiplier suffix, optionally followed by "B"
+var sizeLiteralPat = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(?:([kKMGTPE])(i?))?[bB]?$`)
+
+// Pattern to parse a duration like "30d" or "-1.5h"
+var durationLiteralPat = regexp.MustCompile(`^([+-]?)(\d+(?:\.\d+)?)([smhdwy])$`)
+
+// Lengths of the duration literal units
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// Layouts accepted for absolute date literals; dates without a time zone are
+// in UTC, like the mtime column
+var dateLiteralLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Parse an integer that may have a size suffix, like "10M" (10*1000^2) or
+// "1.5GiB" (1.5*1024^3).
+func parseSizeLiteral(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return n, nil
+	}
+	parts := sizeLiteralPat.FindStringSubmatch(s)
+	if parts == nil {
+		return 0, err
+	}
+	f, _ := strconv.ParseFloat(parts[1], 64)
+	if parts[2] != "" {
+		base := 1000.0
+		if parts[3] != "" {
+			base = 1024.0
+		}
+		power := strings.IndexByte("KMGTPE", strings.ToUpper(parts[2])[0]) + 1
+		for i := 0; i < power; i++ {
+			f *= base
+		}
+	}
+	return int64(f), nil
+}
+
+// Parse a duration literal like "30d" or "-2h". Returns false if the string
+// doesn't look like a duration.
+func parseDurationLiteral(s string) (time.Duration, bool) {
+	parts := durationLiteralPat.FindStringSubmatch(s)
+	if parts == nil {
+		return 0, false
+	}
+	f, _ := strconv.ParseFloat(parts[2], 64)
+	d := time.Duration(f * float64(durationUnits[parts[3]]))
+	if parts[1] == "-" {
+		d = -d
+	}
+	return d, true
+}
+
+// Parse an absolute date literal like "2024-01-01" or "2024-01-01T10:00".
+// Returns false if the string doesn't look like a date.
+func parseDateLiteral(s string) (time.Time, bool) {
+	for _, layout := range dateLiteralLayouts {
+		if tm, err := time.Parse(layout, s); err == nil {
+			return tm, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Parse a filter comparison value for the given column. Numeric columns accept
+// size suffixes. The time columns accept dates, and signed durations which
+// are relative to the run start time; for these the returned value is a
+// time.Duration to be resolved later, and the returned flag is set. The age
+// column accepts durations. Other string columns use the value as-is.
+func parseFilterValue(col Column, data string) (interface{}, bool, error) {
+	switch col {
+	case ColMtime:
+		if d, ok := parseDurationLiteral(data); ok && (data[0] == '-' || data[0] == '+') {
+			return d, true, nil
+		}
+		if tm, ok := parseDateLiteral(data); ok {
+			return timeToMtime(tm, nil), false, nil
+		}
+		return data, false, nil
+	case ColMstamp:
+		if d, ok := parseDurationLiteral(data); ok && (data[0] == '-' || data[0] == '+') {
+			return d, true, nil
+		}
+		if tm, ok := parseDateLiteral(data); ok {
+			return tm.Unix(), false, nil
+		}
+		n, err := strconv.ParseInt(data, 10, 64)
+		return n, false, err
+	case ColAge:
+		if d, ok := parseDurationLiteral(data); ok {
+			return int64(d / time.Second), false, nil
+		}
+		n, err := strconv.ParseInt(data, 10, 64)
+		return n, false, err
+	}
+	if col.isNumeric() {
+		n, err := parseSizeLiteral(data)
+		return n, false, err
+	}
+	return data, false, nil
+}
+
+// Finish setting up the values in a filter tree that depend on the run:
+// values relative to the run start time are made absolute, and the sets for
+// "in" filters are loaded from their files.
+func (self *Context) resolveFilterValues(filt *Filter) error {
+	if filt == nil {
+		return nil
+	}
+	switch filt.op {
+	case opAnd, opOr:
+		if err := self.resolveFilterValues(filt.left); err != nil {
+			return err
+		}
+		return self.resolveFilterValues(filt.right)
+	case opIn:
+		if filt.set == nil {
+			var err error
+			path := strings.TrimPrefix(filt.value.(string), "@")
+			filt.set, err = self.loadFilterSet(filt.column, path)
+			return err
+		}
+	}
+	if filt.relTime {
+		tm := self.startTime.Add(filt.value.(time.Duration))
+		if filt.column == ColMstamp {
+			filt.value = tm.Unix()
+		} else {
+			filt.value = timeToMtime(tm, nil)
+		}
+		filt.relTime = false
+	}
+	return nil
+}
+
+// Compute the age of a file in seconds from its modification time, relative
+// to the run start time.
+func (self *Context) ageOf(mtime time.Time) int64 {
+	return int64(self.startTime.Sub(mtime) / time.Second)
+}
@@ -0,0 +1,124 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseSizeLiteral(t *testing.T) {
+	var tests = []struct {
+		arg     string
+		want    int64
+		wantErr string
+	}{
+		{"123", 123, ""},
+		{"-5", -5, ""},
+		{"10K", 10000, ""},
+		{"10k", 10000, ""},
+		{"10M", 10000000, ""},
+		{"10MB", 10000000, ""},
+		{"2G", 2000000000, ""},
+		{"1Ki", 1024, ""},
+		{"1.5GiB", 1610612736, ""},
+		{"3 MiB", 3145728, ""},
+		{"1T", 1000000000000, ""},
+		{"100B", 100, ""},
+		{"10X", 0, "strconv.ParseInt"},
+		{"M", 0, "strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		got, err := parseSizeLiteral(test.arg)
+		checkValErr1(t, test.want, got, test.wantErr, err)
+	}
+}
+
+func Test_parseDurationLiteral(t *testing.T) {
+	var tests = []struct {
+		arg    string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"30s", 30 * time.Second, true},
+		{"5m", 5 * time.Minute, true},
+		{"-2h", -2 * time.Hour, true},
+		{"+1.5d", 36 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"1y", 365 * 24 * time.Hour, true},
+		{"10", 0, false},
+		{"d", 0, false},
+		{"1x", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseDurationLiteral(test.arg)
+		checkVal(t, test.want, got)
+		checkVal(t, test.wantOk, ok)
+	}
+}
+
+func Test_parseFilterValue(t *testing.T) {
+	var tests = []struct {
+		col         Column
+		arg         string
+		want        interface{}
+		wantRelTime bool
+		wantErr     string
+	}{
+		{ColSize, "10M", int64(10000000), false, ""},
+		{ColNlinks, "2", int64(2), false, ""},
+		{ColPath, "10M", "10M", false, ""},
+		{ColMtime, "2024-01-01", "2024-01-01T00:00:00Z", false, ""},
+		{ColMtime, "2024-01-01T10:00", "2024-01-01T10:00:00Z", false, ""},
+		{ColMtime, "2024-01-01 10:00:05", "2024-01-01T10:00:05Z", false, ""},
+		{ColMtime, "2024-01-01T10:00:00+02:00", "2024-01-01T08:00:00Z", false, ""},
+		{ColMtime, "-7d", -7 * 24 * time.Hour, true, ""},
+		{ColMtime, "7d", "7d", false, ""}, // durations must be signed for dates
+		{ColMtime, "2024", "2024", false, ""},
+		{ColMstamp, "1970-01-02", int64(86400), false, ""},
+		{ColMstamp, "-1h", -time.Hour, true, ""},
+		{ColMstamp, "100", int64(100), false, ""},
+		{ColMstamp, "x", int64(0), false, "strconv.ParseInt"},
+		{ColAge, "30d", int64(30 * 86400), false, ""},
+		{ColAge, "90", int64(90), false, ""},
+		{ColAge, "1M", int64(0), false, "strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		got, relTime, err := parseFilterValue(test.col, test.arg)
+		checkValErr1(t, test.want, got, test.wantErr, err)
+		checkVal(t, test.wantRelTime, relTime)
+	}
+}
+
+func Test_Context_resolveFilterValues(t *testing.T) {
+	ctx := NewContext()
+	ctx.startTime = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	filt, err := ParseFilterExpr("mtime > -2d and (mstamp < +1h or age < 1w) and size > 1K")
+	checkValErr1(t, nil, nil, "", err)
+	err = ctx.resolveFilterValues(filt)
+	checkValErr1(t, nil, nil, "", err)
+	checkVal(t, "and(and(!mtime<=2024-03-08T12:00:00Z,or(mstamp<#1710075600,age<#604800)),!size<=#1000)",
+		describeFilter(filt))
+
+	entry := fileEntry{ColMtime: "2024-03-09T00:00:00Z", ColMstamp: int64(1709942400), ColAge: int64(129600), ColSize: int64(2000)}
+	match, ok := filt.filter(entry)
+	checkVal(t, true, match)
+	checkVal(t, true, ok)
+	checkVal(t, int64(129600), ctx.ageOf(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,166 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Values of the merge column
+const (
+	mergeUnchanged = "unchanged"
+	mergeChangedB  = "changed-B"
+	mergeChangedC  = "changed-C"
+	mergeBoth      = "changed-both"
+	mergeConflict  = "conflict"
+)
+
+// Sides of a three-way merge
+const (
+	mergeBase  = 0 // the common base, usually a saved FSIFT file
+	mergeLeft  = 1 // side B, one of the current trees
+	mergeRight = 2 // side C, the other current tree
+)
+
+// Return true if the entries with the same identity on two sides are the
+// same according to the compare key columns. Entries that are both missing
+// are also the same.
+func (self *Context) sameEntries(e1, e2 fileEntry) bool {
+	if e1 == nil || e2 == nil {
+		return e1 == nil && e2 == nil
+	}
+	return self.changedColumns(e1, e2) == ""
+}
+
+// Classify the entries with the same identity on the base side and the two
+// current sides of a three-way merge, and return the merge value and the
+// action to sync the current sides. The entries may be nil if missing.
+func (self *Context) mergeAction(base, left, right fileEntry) (string, string) {
+	leftSame := self.sameEntries(base, left)
+	rightSame := self.sameEntries(base, right)
+	switch {
+	case leftSame && rightSame:
+		return mergeUnchanged, ""
+	case rightSame && left == nil:
+		return mergeChangedB, "delete:C"
+	case rightSame:
+		return mergeChangedB, "copy:B>C"
+	case leftSame && right == nil:
+		return mergeChangedC, "delete:B"
+	case leftSame:
+		return mergeChangedC, "copy:C>B"
+	case self.sameEntries(left, right):
+		return mergeBoth, ""
+	}
+	return mergeConflict, "conflict"
+}
+
+// Pair the entries on the three sides of a three-way merge by the identity
+// key columns, and classify each group, setting the merge and mergeaction
+// columns, updating the statistics for each kind of merge, and saving the
+// action list. If keys is not nil, it holds the entries to compare in place
+// of each entry. Entries are compared with the first entry with the same
+// identity on each other side.
+func (self *Context) classifyMerge(entries, keys []fileEntry) {
+	cmp := entries
+	if keys != nil {
+		cmp = keys
+	}
+	// sort indexes by identity, leaving the entries in place
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		d, notNull := cmp[order[a]].compare(cmp[order[b]], self.IdentityCols.cols)
+		self.checkNullCompare(notNull)
+		return d < 0
+	})
+
+	start := 0
+	for end := 1; end <= len(order); end++ {
+		if end < len(order) {
+			d, _ := cmp[order[start]].compare(cmp[order[end]], self.IdentityCols.cols)
+			if d == 0 {
+				continue
+			}
+		}
+		// find the first entry on each side with this identity
+		var first [3]fileEntry
+		var path [3]string
+		for _, i := range order[start:end] {
+			if s := sideIndex(entries[i]); s < len(first) && first[s] == nil {
+				first[s] = cmp[i]
+				path[s] = entryPath(entries[i])
+			}
+		}
+		merge, action := self.mergeAction(first[mergeBase], first[mergeLeft], first[mergeRight])
+		for _, i := range order[start:end] {
+			entries[i].setStringField(ColMerge, merge)
+			entries[i].setStringField(ColMergeAct, action)
+			size := entries[i].getNumericFieldOrZero(ColSize)
+			if strings.HasSuffix(entryPath(entries[i]), "/") {
+				size = 0
+			}
+			self.mergeStats(merge).update(sideIndex(entries[i]), size)
+		}
+		if action != "" {
+			// use the path on the side the action reads from, if any
+			p := path[mergeLeft]
+			if strings.HasSuffix(action, "C>B") || p == "" {
+				p = path[mergeRight]
+			}
+			if p == "" {
+				p = path[mergeBase]
+			}
+			self.mergeActions = append(self.mergeActions, action+" "+escapeField(p, true, true))
+		}
+		start = end
+	}
+}
+
+// Return the statistics object for a kind of merge.
+func (self *Context) mergeStats(merge string) *stats {
+	switch merge {
+	case mergeUnchanged:
+		return &self.unchangedStats
+	case mergeChangedB:
+		return &self.changedBStats
+	case mergeChangedC:
+		return &self.changedCStats
+	case mergeBoth:
+		return &self.bothStats
+	}
+	return &self.conflictStats
+}
+
+// Write the three-way merge action list to the --merge-actions file: a line
+// for each identity with an action, and its path escaped like the last
+// column of a FSIFT file.
+func (self *Context) writeMergeActions() {
+	var text string
+	if len(self.mergeActions) > 0 {
+		text = strings.Join(self.mergeActions, "\n") + "\n"
+	}
+	if err := ioutil.WriteFile(self.MergeActions, []byte(text), 0666); err != nil {
+		self.onError("Error writing merge action list:", err)
+	}
+}
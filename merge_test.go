@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Context_mergeAction(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize}}
+	e1 := fileEntry{ColPath: "a", ColSize: int64(1)}
+	e2 := fileEntry{ColPath: "a", ColSize: int64(2)}
+	e3 := fileEntry{ColPath: "a", ColSize: int64(3)}
+	var tests = []struct {
+		base, left, right fileEntry
+		merge, action     string
+	}{
+		{e1, e1, e1, "unchanged", ""},
+		{e1, e2, e1, "changed-B", "copy:B>C"},
+		{e1, nil, e1, "changed-B", "delete:C"},
+		{nil, e1, nil, "changed-B", "copy:B>C"},
+		{e1, e1, e2, "changed-C", "copy:C>B"},
+		{e1, e1, nil, "changed-C", "delete:B"},
+		{e1, e2, e2, "changed-both", ""},
+		{e1, nil, nil, "changed-both", ""},
+		{e1, e2, e3, "conflict", "conflict"},
+		{e1, e2, nil, "conflict", "conflict"},
+		{nil, e2, e3, "conflict", "conflict"},
+	}
+	for _, test := range tests {
+		merge, action := ctx.mergeAction(test.base, test.left, test.right)
+		checkVal(t, test.merge, merge)
+		checkVal(t, test.action, action)
+	}
+}
+
+func Test_Context_classifyMerge(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize}}
+	ctx.IdentityCols = ColSelector{cols: []Column{ColPath}}
+	ctx.Merge = true
+	ctx.entries = []fileEntry{
+		{ColPath: "same", ColSize: int64(1), ColSide: int64(0)},
+		{ColPath: "same", ColSize: int64(1), ColSide: int64(1)},
+		{ColPath: "same", ColSize: int64(1), ColSide: int64(2)},
+		{ColPath: "b", ColSize: int64(1), ColSide: int64(0)},
+		{ColPath: "b", ColSize: int64(2), ColSide: int64(1)},
+		{ColPath: "b", ColSize: int64(1), ColSide: int64(2)},
+		{ColPath: "c", ColSize: int64(1), ColSide: int64(0)},
+		{ColPath: "c", ColSize: int64(1), ColSide: int64(1)},
+		{ColPath: "x y", ColSize: int64(3), ColSide: int64(1)},
+		{ColPath: "x y", ColSize: int64(4), ColSide: int64(2)},
+	}
+	ctx.classifyMerge(ctx.entries, nil)
+	var merges, actions []string
+	for _, entry := range ctx.entries {
+		m, _ := entry.getStringField(ColMerge)
+		a, _ := entry.getStringField(ColMergeAct)
+		merges = append(merges, m)
+		actions = append(actions, a)
+	}
+	checkVal(t, []string{"unchanged", "unchanged", "unchanged", "changed-B", "changed-B", "changed-B",
+		"changed-C", "changed-C", "conflict", "conflict"}, merges)
+	checkVal(t, []string{"", "", "", "copy:B>C", "copy:B>C", "copy:B>C",
+		"delete:B", "delete:B", "conflict", "conflict"}, actions)
+	checkVal(t, []string{"copy:B>C b", "delete:B c", "conflict x y"}, ctx.mergeActions)
+	count, size := ctx.changedBStats.get(1)
+	checkVal(t, int64(1), count)
+	checkVal(t, int64(2), size)
+
+	dir, err := ioutil.TempDir("", "fsift-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx.MergeActions = filepath.Join(dir, "actions")
+	ctx.writeMergeActions()
+	data, _ := ioutil.ReadFile(ctx.MergeActions)
+	checkVal(t, "copy:B>C b\ndelete:B c\nconflict x y\n", string(data))
+}
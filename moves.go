@@ -0,0 +1,136 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"path"
+	"sort"
+)
+
+// Digest columns that can identify file content for move detection, in
+// order of preference
+var moveDigestColumns = []Column{ColSha512, ColSha256, ColSha1, ColMd5, ColGitBlob}
+
+// Choose the digest column used to detect moves: the first one already
+// needed for the run, otherwise md5.
+func (self *Context) chooseMoveDigest() Column {
+	for _, col := range moveDigestColumns {
+		if self.needsCol(col) {
+			return col
+		}
+	}
+	return ColMd5
+}
+
+// Identifies file content for move detection
+type contentKey struct {
+	size   int64
+	digest string
+}
+
+// Pair up unmatched entries on the left side with unmatched entries on the
+// right side that have the same content but a different path, and mark them
+// as moved by setting their counterpart columns to each other's paths.
+// Entries with the same base name are paired first, so moves of several
+// copies aren't crossed with each other. Empty files are never paired.
+func (self *Context) detectMoves(entries []fileEntry) {
+	// collect unmatched regular files by content and side
+	var keys []contentKey
+	groups := map[contentKey]*[2][]fileEntry{}
+	for _, entry := range entries {
+		entry.setStringField(ColOtherPath, "") // not moved unless paired below
+		if entry.getBoolFieldOrFalse(ColMatched) {
+			continue
+		}
+		digest, ok := entry.getStringField(self.moveDigest)
+		size := entry.getNumericFieldOrZero(ColSize)
+		if !ok || digest == "" || size == 0 {
+			continue // not a regular file, or has no content to identify
+		}
+		key := contentKey{size, digest}
+		group := groups[key]
+		if group == nil {
+			group = &[2][]fileEntry{}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		side := sideIndex(entry)
+		group[side] = append(group[side], entry)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group[0]) == 0 || len(group[1]) == 0 {
+			continue
+		}
+		for _, side := range group {
+			sort.Slice(side, func(i, j int) bool { return entryPath(side[i]) < entryPath(side[j]) })
+		}
+		left, right := group[0], group[1]
+		// files at the same path weren't moved, just changed in other ways
+		left, right = pairMoves(left, right, entryPath, nil)
+		// then pair files with the same base name in other directories, then
+		// everything else
+		left, right = pairMoves(left, right, func(e fileEntry) string { return path.Base(entryPath(e)) }, setMoved)
+		pairMoves(left, right, func(e fileEntry) string { return "" }, setMoved)
+	}
+}
+
+// Return the path of an entry, or "" if it has none.
+func entryPath(entry fileEntry) string {
+	p, _ := entry.getStringField(ColPath)
+	return p
+}
+
+// Mark a pair of entries as moved.
+func setMoved(left, right fileEntry) {
+	left.setStringField(ColOtherPath, entryPath(right))
+	right.setStringField(ColOtherPath, entryPath(left))
+}
+
+// Pair entries from the left and right lists whose values of the given
+// function are equal, in order, calling pair (if not nil) for each pair.
+// Returns the entries left unpaired on each side.
+func pairMoves(left, right []fileEntry, value func(fileEntry) string, pair func(l, r fileEntry)) ([]fileEntry, []fileEntry) {
+	var restLeft []fileEntry
+	used := make([]bool, len(right))
+	for _, l := range left {
+		lv := value(l)
+		found := false
+		for j, r := range right {
+			if !used[j] && value(r) == lv {
+				used[j], found = true, true
+				if pair != nil {
+					pair(l, r)
+				}
+				break
+			}
+		}
+		if !found {
+			restLeft = append(restLeft, l)
+		}
+	}
+	var restRight []fileEntry
+	for j, r := range right {
+		if !used[j] {
+			restRight = append(restRight, r)
+		}
+	}
+	return restLeft, restRight
+}
@@ -0,0 +1,86 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func Test_Context_chooseMoveDigest(t *testing.T) {
+	ctx := NewContext()
+	checkVal(t, Column(ColMd5), ctx.chooseMoveDigest())
+	ctx.neededCols = map[Column]bool{ColGitBlob: true, ColSha1: true}
+	checkVal(t, Column(ColSha1), ctx.chooseMoveDigest())
+}
+
+func Test_pairMoves(t *testing.T) {
+	left := []fileEntry{{ColPath: "a/x"}, {ColPath: "b/y"}, {ColPath: "c/z"}}
+	right := []fileEntry{{ColPath: "d/y"}, {ColPath: "e/w"}}
+	var pairs []string
+	restLeft, restRight := pairMoves(left, right, func(e fileEntry) string { return e[ColPath].(string)[2:] },
+		func(l, r fileEntry) { pairs = append(pairs, entryPath(l)+">"+entryPath(r)) })
+	checkVal(t, []string{"b/y>d/y"}, pairs)
+	checkVal(t, []fileEntry{{ColPath: "a/x"}, {ColPath: "c/z"}}, restLeft)
+	checkVal(t, []fileEntry{{ColPath: "e/w"}}, restRight)
+}
+
+func Test_Context_detectMoves(t *testing.T) {
+	ctx := NewContext()
+	ctx.outputState.errStream = ioutil.Discard
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize, ColMd5}}
+	ctx.DetectMoves = true
+	ctx.moveDigest = ColMd5
+	ctx.entries = []fileEntry{
+		{ColPath: "a", ColSize: int64(1), ColMd5: "11", ColSide: int64(0)}, // unchanged
+		{ColPath: "a", ColSize: int64(1), ColMd5: "11", ColSide: int64(1)},
+		{ColPath: "old/b", ColSize: int64(2), ColMd5: "22", ColSide: int64(0)}, // moved
+		{ColPath: "new/b", ColSize: int64(2), ColMd5: "22", ColSide: int64(1)},
+		{ColPath: "c1", ColSize: int64(3), ColMd5: "33", ColSide: int64(0)}, // renamed
+		{ColPath: "c2", ColSize: int64(3), ColMd5: "33", ColSide: int64(1)},
+		{ColPath: "x/d", ColSize: int64(4), ColMd5: "44", ColSide: int64(0)}, // two copies moved
+		{ColPath: "y/d", ColSize: int64(4), ColMd5: "44", ColSide: int64(0)},
+		{ColPath: "z/e", ColSize: int64(4), ColMd5: "44", ColSide: int64(1)},
+		{ColPath: "z/d", ColSize: int64(4), ColMd5: "44", ColSide: int64(1)},
+		{ColPath: "f", ColSize: int64(5), ColMd5: "55", ColSide: int64(0)}, // changed in place
+		{ColPath: "f", ColSize: int64(5), ColMd5: "55", ColSide: int64(1), ColUid: int64(1)},
+		{ColPath: "g", ColSize: int64(6), ColMd5: "66", ColSide: int64(0)}, // deleted
+		{ColPath: "h", ColSize: int64(6), ColMd5: "67", ColSide: int64(1)}, // added
+		{ColPath: "i", ColSize: int64(0), ColMd5: "00", ColSide: int64(0)}, // empty files aren't moves
+		{ColPath: "j", ColSize: int64(0), ColMd5: "00", ColSide: int64(1)},
+		{ColPath: "k/", ColSize: int64(7), ColMd5: "", ColSide: int64(0)}, // dirs aren't moves
+		{ColPath: "l/", ColSize: int64(7), ColMd5: "", ColSide: int64(1)},
+	}
+	ctx.KeyCols.cols = append(ctx.KeyCols.cols, ColUid)
+	ctx.entries[0][ColUid] = int64(0)
+	ctx.entries[1][ColUid] = int64(0)
+	ctx.analyzeMatches()
+
+	var members, others []string
+	for _, entry := range ctx.entries {
+		m, _ := entry.getStringField(ColMembership)
+		o, _ := entry.getStringField(ColOtherPath)
+		members = append(members, m)
+		others = append(others, o)
+	}
+	checkVal(t, []string{"<=", ">=", "<~", ">~", "<~", ">~", "<~", "<~", ">~", ">~",
+		"<!", ">!", "<!", ">!", "<!", ">!", "<!", ">!"}, members)
+	checkVal(t, []string{"", "", "new/b", "old/b", "c2", "c1", "z/d", "z/e", "y/d", "x/d",
+		"", "", "", "", "", "", "", ""}, others)
+}
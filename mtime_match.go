@@ -0,0 +1,195 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"sort"
+)
+
+// Seconds in an hour; the unit of the offsets allowed by MtimeHours
+const secsPerHour = 3600
+
+// Return true if mtimes are compared with a tolerance in this run: some
+// tolerance is set and a time column is part of the compare key.
+func (self *Context) usesMtimeTolerance() bool {
+	if self.MtimeTolerance <= 0 && self.MtimeHours <= 0 {
+		return false
+	}
+	for _, col := range self.KeyCols.cols {
+		col &^= ColInvertFlag
+		if col == ColMtime || col == ColMstamp {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the times t1 and t2 are equal within the mtime tolerance and
+// hour offsets, if any.
+func (self *Context) mtimesMatch(t1, t2 int64) bool {
+	for h := int64(0); h <= self.MtimeHours; h++ {
+		for _, off := range []int64{h * secsPerHour, -h * secsPerHour} {
+			d := t2 - t1 - off
+			if d >= -self.MtimeTolerance && d <= self.MtimeTolerance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Return the given key columns without the time columns.
+func withoutTimeCols(cols []Column) []Column {
+	var out []Column
+	for _, col := range cols {
+		if c := col &^ ColInvertFlag; c != ColMtime && c != ColMstamp {
+			out = append(out, col)
+		}
+	}
+	return out
+}
+
+// Return the columns used to sort entries before finding match groups. With
+// a tolerance, entries are sorted by the other key columns and then by time,
+// so that all entries which may match are adjacent.
+func (self *Context) matchSortCols() []Column {
+	if !self.usesMtimeTolerance() {
+		return self.KeyCols.cols
+	}
+	return append(withoutTimeCols(self.KeyCols.cols), ColMstamp)
+}
+
+// Determine the match groups of a list of entries already sorted with the
+// columns from matchSortCols. If keys is not nil, it holds the entries to
+// compare in place of each entry. Entries (and keys) may be reordered so that
+// each group is contiguous. Returns a list parallel to the entries holding an
+// ID for each entry's group. With --verify-content, groups are split by the
+// content of their files.
+func (self *Context) findMatchGroups(entries, keys []fileEntry) []int {
+	groups := make([]int, len(entries))
+	cmp := entries
+	if keys != nil {
+		cmp = keys
+	}
+	if !self.usesMtimeTolerance() {
+		// exact comparison; groups are runs of equal entries
+		base := 0
+		for cur := 1; cur < len(entries); cur++ {
+			d, notNull := cmp[base].compare(cmp[cur], self.KeyCols.cols)
+			self.checkNullCompare(notNull)
+			if d != 0 {
+				base = cur
+			}
+			groups[cur] = base
+		}
+		if self.VerifyContent {
+			self.verifyMatchGroups(entries, keys, groups)
+		}
+		return groups
+	}
+
+	// find runs of entries equal in the other key columns, then group each
+	// run by time
+	keyCols := withoutTimeCols(self.KeyCols.cols)
+	start := 0
+	for end := 1; end <= len(entries); end++ {
+		if end < len(entries) {
+			d, notNull := cmp[start].compare(cmp[end], keyCols)
+			self.checkNullCompare(notNull)
+			if d == 0 {
+				continue
+			}
+		}
+		var keyRun []fileEntry
+		if keys != nil {
+			keyRun = keys[start:end]
+		}
+		self.groupByMtime(entries[start:end], keyRun, groups[start:end], start)
+		start = end
+	}
+	if self.VerifyContent {
+		self.verifyMatchGroups(entries, keys, groups)
+	}
+	return groups
+}
+
+// Group a run of entries sorted by time. Each group starts at the earliest
+// entry not yet grouped, and takes the later entries whose times are within
+// the tolerance of that first entry's time (or of a whole hour offset from
+// it). Matching isn't transitive: a chain of entries each within the
+// tolerance of the next can span several groups. The entries are reordered
+// so each group is contiguous, along with keys if not nil, and the group IDs
+// (which start at firstId) are stored in groups.
+func (self *Context) groupByMtime(run, keys []fileEntry, groups []int, firstId int) {
+	n := len(run)
+	stamps := make([]int64, n)
+	hasStamp := make([]bool, n)
+	for i, entry := range run {
+		stamps[i], hasStamp[i] = entry.getNumericField(ColMstamp)
+	}
+	first := make([]int, n) // index of the first entry of each entry's group
+	for i := range first {
+		first[i] = -1
+	}
+	for i := 0; i < n; i++ {
+		if !hasStamp[i] {
+			// entries without times sort first; they match each other
+			self.checkNullCompare(false)
+			first[i] = 0
+			continue
+		}
+		if first[i] >= 0 {
+			continue
+		}
+		// start a new group, and add the later entries in each window
+		first[i] = i
+		for h := int64(0); h <= self.MtimeHours; h++ {
+			lo := stamps[i] + h*secsPerHour - self.MtimeTolerance
+			hi := stamps[i] + h*secsPerHour + self.MtimeTolerance
+			j := sort.Search(n, func(k int) bool { return hasStamp[k] && stamps[k] >= lo })
+			for ; j < n && stamps[j] <= hi; j++ {
+				if j > i && first[j] < 0 {
+					first[j] = i
+				}
+			}
+		}
+	}
+
+	// reorder the run so that groups are contiguous, in order of their
+	// first entries
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return first[order[a]] < first[order[b]] })
+	sorted := make([]fileEntry, n)
+	var sortedKeys []fileEntry
+	if keys != nil {
+		sortedKeys = make([]fileEntry, n)
+	}
+	for i, k := range order {
+		sorted[i] = run[k]
+		if keys != nil {
+			sortedKeys[i] = keys[k]
+		}
+		groups[i] = firstId + first[k]
+	}
+	copy(run, sorted)
+	copy(keys, sortedKeys)
+}
@@ -0,0 +1,105 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func Test_Context_matchSortCols(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColMtime | ColInvertFlag, ColSize}}
+	checkVal(t, []Column{ColPath, ColMtime | ColInvertFlag, ColSize}, ctx.matchSortCols())
+	ctx.MtimeTolerance = 2
+	checkVal(t, []Column{ColPath, ColSize, ColMstamp}, ctx.matchSortCols())
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColSize}}
+	checkVal(t, false, ctx.usesMtimeTolerance())
+	checkVal(t, []Column{ColPath, ColSize}, ctx.matchSortCols())
+}
+
+func Test_Context_analyzeMatchesMtimeTolerance(t *testing.T) {
+	var tests = []struct {
+		tolerance int64
+		hours     int64
+		want      []int64
+	}{
+		{0, 0, []int64{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 0, 0, 0, 0}},
+		{2, 0, []int64{1, 1, 0, 0, 0, 0, 0, 0, 1, 1, 1, 0, 1, 1, 1, 0}},
+		{2, 1, []int64{1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 1, 0, 1, 1, 1, 0}},
+		{0, 2, []int64{0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.outputState.errStream = ioutil.Discard
+		ctx.KeyCols = ColSelector{cols: []Column{ColPath, ColMtime}}
+		ctx.MtimeTolerance = test.tolerance
+		ctx.MtimeHours = test.hours
+		ctx.entries = []fileEntry{
+			{ColPath: "a", ColMstamp: int64(1000), ColSide: int64(0)},
+			{ColPath: "a", ColMstamp: int64(1002), ColSide: int64(1)}, // within 2s
+			{ColPath: "b", ColMstamp: int64(1000), ColSide: int64(0)},
+			{ColPath: "b", ColMstamp: int64(1003), ColSide: int64(1)}, // 3s off
+			{ColPath: "c", ColMstamp: int64(5000), ColSide: int64(0)},
+			{ColPath: "c", ColMstamp: int64(8601), ColSide: int64(1)}, // 1h + 1s off
+			{ColPath: "d", ColMstamp: int64(1000), ColSide: int64(0)},
+			{ColPath: "d", ColMstamp: int64(8200), ColSide: int64(1)}, // 2h off
+			{ColPath: "e", ColMstamp: int64(1000), ColSide: int64(0)},
+			{ColPath: "e", ColMstamp: int64(1000), ColSide: int64(1)}, // exact
+			{ColPath: "f", ColMstamp: int64(1000), ColSide: int64(0)},
+			{ColPath: "f", ColMstamp: int64(1004), ColSide: int64(0)},
+			{ColPath: "f", ColMstamp: int64(1002), ColSide: int64(1)}, // only within 2s of the first
+			{ColPath: "g", ColMstamp: int64(2000), ColSide: int64(0)},
+			{ColPath: "g", ColMstamp: int64(2002), ColSide: int64(1)},
+			{ColPath: "g", ColMstamp: int64(2004), ColSide: int64(0)}, // near 2002, not 2000
+		}
+		ctx.analyzeMatches()
+		var got []int64
+		for _, entry := range ctx.entries {
+			got = append(got, entry[ColMatched].(int64))
+		}
+		checkVal(t, test.want, got)
+	}
+}
+
+func Test_Context_mtimesMatch(t *testing.T) {
+	var tests = []struct {
+		tolerance int64
+		hours     int64
+		t1, t2    int64
+		want      bool
+	}{
+		{0, 0, 100, 100, true},
+		{0, 0, 100, 101, false},
+		{2, 0, 100, 98, true},
+		{2, 0, 100, 103, false},
+		{0, 1, 100, 3700, true},
+		{0, 1, 3700, 100, true},
+		{2, 1, 100, 3702, true},
+		{2, 1, 100, 3703, false},
+		{0, 1, 100, 7300, false},
+		{0, 2, 100, 7300, true},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.MtimeTolerance = test.tolerance
+		ctx.MtimeHours = test.hours
+		checkVal(t, test.want, ctx.mtimesMatch(test.t1, test.t2))
+	}
+}
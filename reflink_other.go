@@ -0,0 +1,33 @@
+//go:build !linux || (!386 && !amd64 && !arm && !arm64 && !loong64 && !riscv64 && !s390x)
+// +build !linux !386,!amd64,!arm,!arm64,!loong64,!riscv64,!s390x
+
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"errors"
+	"os"
+)
+
+// Reflinks are only supported on Linux, on the architectures in
+// reflink_linux.go
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	return errors.New("reflinks aren't supported on this platform")
+}
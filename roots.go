@@ -0,0 +1,102 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Start processing a root given on the command line: remember it for the
+// root columns, and start its statistics.
+func (self *Context) startRoot(root string) {
+	self.curRoot = root
+	self.curRootAbs = ""
+	self.rootStats = append(self.rootStats, stats{name: root})
+	self.rootSides = append(self.rootSides, self.CurSide)
+}
+
+// Return the absolute path of a file at relPath under the current root, with
+// a trailing slash for a directory like the path column.
+func (self *Context) absPath(relPath string) string {
+	p := myJoin(self.curRootAbs, relPath)
+	if strings.HasSuffix(relPath, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// Set the root and rootindex columns of an entry if needed, unless it
+// already has them (for example, from the FSIFT file it was loaded from).
+func (self *Context) setRootColumns(entry fileEntry) {
+	if _, ok := entry[ColRoot]; !ok && self.needsCol(ColRoot) {
+		entry.setStringField(ColRoot, self.curRoot)
+	}
+	if _, ok := entry[ColRootIdx]; !ok && self.needsCol(ColRootIdx) {
+		entry.setNumericField(ColRootIdx, int64(len(self.rootStats)))
+	}
+}
+
+// Update the index stats, and the stats for the current root, with a file
+// that was added to the index.
+func (self *Context) updateIndexStats(size int64) {
+	self.indexStats.update(self.CurSide, size)
+	if n := len(self.rootStats); n > 0 {
+		self.rootStats[n-1].update(self.CurSide, size)
+	}
+}
+
+// Format the count and size of the files indexed from each root into a 2D
+// array of strings, padded like the summary statistics. The side is only
+// shown if there are several sides.
+func (self *Context) calcRootInfo() [][]string {
+	showSide := self.numSides() > 1
+	header := []string{"ROOTS:"}
+	if showSide {
+		header = append(header, "Side")
+	}
+	header = append(header, "Count", "Size", "Root")
+	out := [][]string{header}
+	for i, stat := range self.rootStats {
+		line := []string{fmt.Sprint(i + 1)}
+		side := self.rootSides[i]
+		if showSide {
+			line = append(line, self.sideName(side))
+		}
+		count, size := stat.get(side)
+		line = append(line, self.formatNumber(count), self.formatNumber(size), stat.name)
+		out = append(out, line)
+	}
+
+	// pad each item but the root to the max width in its column
+	widths := make([]int, len(header))
+	for _, line := range out {
+		for i, s := range line {
+			if len(s) > widths[i] {
+				widths[i] = len(s)
+			}
+		}
+	}
+	for _, line := range out {
+		for i := 0; i < len(line)-1; i++ {
+			line[i] = fmt.Sprintf("%*s", widths[i], line[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Context_absPath(t *testing.T) {
+	ctx := NewContext()
+	ctx.curRootAbs = "/a/b"
+	checkVal(t, "/a/b/c", ctx.absPath("c"))
+	checkVal(t, "/a/b/c/", ctx.absPath("c/"))
+	checkVal(t, "/a/b/", ctx.absPath("./"))
+	ctx.curRootAbs = "/"
+	checkVal(t, "/", ctx.absPath("./"))
+}
+
+func Test_Context_processRoot_rootColumns(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	ioutil.WriteFile(filepath.Join(dirPath, "a"), []byte("aaa"), 0644)
+	fsiftPath := filepath.Join(dirPath, "saved.FSIFT")
+	ioutil.WriteFile(fsiftPath, []byte(sifterFileHeader+"\n| Columns: size,root,path\n 5 old/root b\n 7 \\~ c\n"), 0644)
+
+	ctx := NewContext()
+	ctx.neededCols = map[Column]bool{ColRoot: true, ColRootIdx: true, ColAbsPath: true}
+	ctx.processRoot(filepath.Join(dirPath, "a"))
+	ctx.CurSide = 1
+	ctx.processRoot(fsiftPath)
+	checkVal(t, 3, len(ctx.entries))
+
+	file := ctx.entries[0]
+	checkVal(t, filepath.Join(dirPath, "a"), file[ColRoot])
+	checkVal(t, int64(1), file[ColRootIdx])
+	abs, _ := filepath.Abs(filepath.Join(dirPath, "a"))
+	checkVal(t, filepath.ToSlash(abs), file[ColAbsPath])
+
+	// a saved root is kept, a null one is replaced
+	checkVal(t, "old/root", ctx.entries[1][ColRoot])
+	checkVal(t, fsiftPath, ctx.entries[2][ColRoot])
+	checkVal(t, int64(2), ctx.entries[2][ColRootIdx])
+	_, ok := ctx.entries[2][ColAbsPath]
+	checkVal(t, false, ok)
+
+	ctx.Roots = map[int][]string{0: {"x"}, 1: {"y"}}
+	checkVal(t, [][]string{
+		{"ROOTS:", "Side", "Count", "Size", "Root"},
+		{"     1", "   L", "    1", "   3", filepath.Join(dirPath, "a")},
+		{"     2", "   R", "    2", "  12", fsiftPath},
+	}, ctx.calcRootInfo())
+}
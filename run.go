@@ -48,34 +48,65 @@ type ColSelector struct {
 // the file sifter program.
 type Context struct {
 	// The following fields are set by the command line program before starting
-	OutCols         ColSelector       // columns to show in output
-	SortCols        ColSelector       // columns to sort by, in order of precedence
-	KeyCols         ColSelector       // columns to use for compare key
-	PreFilterArgs   []*Filter         // filter objects as parsed from command line --prefilter args
-	PostFilterArgs  []*Filter         // filter objects as parsed from command line --postfilter args
-	PruneFilterArgs []*Filter         // filter objects as parsed from command line --prunefilter args
-	Roots           map[bool][]string // lists of root paths, [false] = left side, [true] = right side
-	CurSide         bool              // current side to add roots to (true after ":" command line arg)
-	Verbosity       int               // verbosity level, default=0
-	SummaryOnly     bool              // true to suppress file entry output
-	GroupNumerics   bool              // true to group numbers with commas, like 1,234
-	Plain           bool              // true to suppress header and footer output
-	Plain0          bool              // like Plain, but also use '0x00' to separate fields
-	FollowLinks     bool              // true to follow/use targets of symbolic links
-	RegularOnly     bool              // true to only index regular files
-	AddMd5          bool              // true to add digest columns to output and compare key...
-	AddSha1         bool              // "
-	AddSha256       bool              // "
-	AddSha512       bool              // "
-	JsonOut         bool              // true to output data in JSON format
-	MembershipFilt  string            // add a postfilter based on membership codes [lrLR]
-	IgnoreNullCmps  bool              // suppress warnings about null comparisons
-	Excludes        []*regexp.Regexp  // pattern to exclude files, dir trees by path match
-	OutputPath      string            // output file, if any (default=stdout)
-	NoDetect        bool              // true to suppress autodetect of FSIFT files for roots
-	XDev            bool              // true to prevent descending into directories on different file systems
-	Verify          bool              // true to check that all files on left are matched on right
-	OutputTimezone  *time.Location    // if set, translate output dates to given timezone
+	OutCols         ColSelector      // columns to show in output
+	SortCols        ColSelector      // columns to sort by, in order of precedence
+	KeyCols         ColSelector      // columns to use for compare key
+	IdentityCols    ColSelector      // columns that pair entries on each side to classify changes
+	SideCols        ColSelector      // columns to show for each side in side-by-side output
+	GroupCols       ColSelector      // columns to group output entries by
+	Aggregates      []Aggregate      // aggregate values to output for each group
+	GroupSort       string           // group columns and aggregates to sort groups by
+	Duplicates      bool             // output groups of files with the same compare key on each side
+	Fdupes          bool             // output duplicate groups as lists of paths, like fdupes
+	Dedupe          string           // replace duplicates with "hardlink" or "reflink" links to the kept copy
+	KeepRule        string           // how to choose the copy to keep in each duplicate group
+	DryRun          bool             // only log what --dedupe would change
+	IgnorePerms     bool             // hard link copies whose owner, group or mode differ
+	DedupeLog       string           // path of the file to write the --dedupe log to
+	VerifyContent   bool             // split match groups whose files differ byte for byte
+	SyncPlan        string           // output a plan to sync the right side with the left: sh, rsync or json
+	Limit           int              // max entries to output from each limit group; zero for no limit
+	LimitCols       ColSelector      // columns to group entries by for the limit; all entries are one group if empty
+	Histogram       *Histogram       // buckets to count the output files in, if any
+	HistogramBars   int              // width of the histogram bars; zero for no bars
+	PreFilterArgs   []*Filter        // filter objects as parsed from command line --prefilter args
+	PostFilterArgs  []*Filter        // filter objects as parsed from command line --postfilter args
+	PruneFilterArgs []*Filter        // filter objects as parsed from command line --prunefilter args
+	Roots           map[int][]string // lists of root paths by side, [0] = left side, [1] = right side, ...
+	CurSide         int              // current side to add roots to (next side after each ":" command line arg)
+	Verbosity       int              // verbosity level, default=0
+	SummaryOnly     bool             // true to suppress file entry output
+	GroupNumerics   bool             // true to group numbers with commas, like 1,234
+	Plain           bool             // true to suppress header and footer output
+	Plain0          bool             // like Plain, but also use '0x00' to separate fields
+	FollowLinks     bool             // true to follow/use targets of symbolic links
+	RegularOnly     bool             // true to only index regular files
+	AddMd5          bool             // true to add digest columns to output and compare key...
+	AddSha1         bool             // "
+	AddSha256       bool             // "
+	AddSha512       bool             // "
+	JsonOut         bool             // true to output data in JSON format
+	MembershipFilt  string           // add a postfilter based on membership codes [lrLR]
+	IgnoreNullCmps  bool             // suppress warnings about null comparisons
+	FoldKeys        bool             // compare file name key columns ignoring case and normalization
+	LeftPathMaps    []PathMap        // rules to rewrite left side paths before comparing
+	RightPathMaps   []PathMap        // rules to rewrite right side paths before comparing
+	IdMaps          map[Column]IdMap // maps of left side owner ids to right side ids, by column
+	Merge           bool             // true to classify three sides as base, B and C for a three-way merge
+	MergeActions    string           // if set, file to write the three-way merge action list to
+	DetectMoves     bool             // true to pair unmatched entries with the same content as moves
+	MtimeTolerance  int64            // seconds by which key mtimes may differ and still match
+	MtimeHours      int64            // max whole hours by which key mtimes may be offset and still match
+	Excludes        []*regexp.Regexp // pattern to exclude files, dir trees by path match
+	OutputPath      string           // output file, if any (default=stdout)
+	NoDetect        bool             // true to suppress autodetect of FSIFT files for roots
+	XDev            bool             // true to prevent descending into directories on different file systems
+	Verify          bool             // true to check that all files on left are matched on right
+	OutputTimezone  *time.Location   // if set, translate output dates to given timezone
+	Grep            *regexp.Regexp   // if set, pattern to search for in file contents
+	GrepMaxBytes    int64            // if nonzero, only search this many bytes of each file
+	GrepBinary      bool             // true to also search files that look like binary data
+	EntropySample   int64            // if nonzero, only compute entropy over this many leading bytes
 
 	// Internal fields
 	entries         []fileEntry     // all of the loaded file entries
@@ -90,12 +121,34 @@ type Context struct {
 	unmatchedStats  stats           // stats for files that did not match
 	matchingStats   stats           // stats for files that did match
 	outputStats     stats           // stats for files that were output
+	addedStats      stats           // stats for files classified as added
+	removedStats    stats           // stats for files classified as removed
+	modifiedStats   stats           // stats for files classified as modified
+	unchangedStats  stats           // stats for files classified as unchanged
+	movedStats      stats           // stats for files classified as moved
+	changedBStats   stats           // stats for files changed only on side B of a merge
+	changedCStats   stats           // stats for files changed only on side C of a merge
+	bothStats       stats           // stats for files changed the same on both sides of a merge
+	conflictStats   stats           // stats for files with conflicting merge changes
+	duplicateStats  stats           // stats for files output in duplicate groups
+	reclaimStats    stats           // stats for duplicate copies that could be removed
+	dedupeStats     stats           // stats for duplicate copies replaced by links
 	startTime       time.Time       // run start time
 	warningCount    int             // total warnings
 	warningMessages []string        // warning messages up to limit
 	errorCount      int             // total errors
 	errorMessages   []string        // error messages up to limit
 	nullErrorCount  int             // number of null comparisons made during run
+	moveDigest      Column          // digest column used to identify content for move detection
+	mergeActions    []string        // lines of the three-way merge action list
+	groupSort       []groupSortKey  // the parsed --group-sort keys
+	curRoot         string          // the root being processed, as given on the command line
+	curRootAbs      string          // absolute path of the current root, if it's in the file system
+	rootStats       []stats         // stats for files indexed from each root, named by the root
+	rootSides       []int           // the side of each root in rootStats
+	histStats       map[int]*stats  // stats for files output in each histogram bucket
+	verified        contentCache    // whether pairs of files have the same content
+	unverified      bool            // true if any file's content couldn't be verified
 	outputFile      *os.File        // if writing to a file, the handle so it can be closed
 	outputState                     // output thread management object
 }
@@ -120,7 +173,7 @@ func mtimeToTime(mtime string) (time.Time, error) {
 // must eventually be called to avoid leaking a goroutine.
 func NewContext() *Context {
 	ctx := Context{
-		Roots: map[bool][]string{},
+		Roots: map[int][]string{},
 	}
 	ctx.OutCols.defauls = []Column{ColModestr, ColSize, ColMtime, ColPath}
 	ctx.KeyCols.defauls = []Column{ColPath, ColSize, ColMtime, ColModestr}
@@ -134,6 +187,18 @@ func NewContext() *Context {
 	ctx.unmatchedStats.name = "Unmatched:"
 	ctx.matchingStats.name = "Matching:"
 	ctx.outputStats.name = "Output:"
+	ctx.addedStats.name = "Added:"
+	ctx.removedStats.name = "Removed:"
+	ctx.modifiedStats.name = "Modified:"
+	ctx.unchangedStats.name = "Unchanged:"
+	ctx.movedStats.name = "Moved:"
+	ctx.changedBStats.name = "Changed B:"
+	ctx.changedCStats.name = "Changed C:"
+	ctx.bothStats.name = "Both same:"
+	ctx.conflictStats.name = "Conflict:"
+	ctx.duplicateStats.name = "Duplicates:"
+	ctx.reclaimStats.name = "Reclaimable:"
+	ctx.dedupeStats.name = "Deduped:"
 	if !unitTest {
 		// start the output thread
 		ctx.outputState.msgChan = make(chan message, 50)
@@ -239,16 +304,63 @@ func (self *Context) adjustCmdlineOptions() {
 	}
 
 	// check for roots on each side
-	hasLeft := len(self.Roots[false]) != 0
-	hasRight := len(self.Roots[true]) != 0
+	nSides := self.numSides()
+	hasLeft := len(self.Roots[0]) != 0
+	if nSides > maxSides {
+		self.fatal("At most", maxSides, "sides can be compared")
+	}
 
-	if hasLeft && hasRight {
-		// if both sides are present, we add membership column by default
+	if hasLeft && nSides > 1 {
+		// if several sides are present, we add membership column by default
 		self.UpdateColumnsCmdlineArg(&self.OutCols, 0, "+membership")
 	} else {
 		// make sure output defaults are set if no options were given
 		self.UpdateColumnsCmdlineArg(&self.OutCols, 0, "+")
 	}
+	// a sync plan replaces all other output
+	if self.SyncPlan != "" {
+		if !syncFormats[self.SyncPlan] {
+			self.fatal("--sync-plan must be sh, rsync or json")
+		}
+		if nSides != 2 || len(self.Roots[0]) != 1 || len(self.Roots[1]) != 1 {
+			self.fatal("A sync plan needs one root on each side: source : destination")
+		}
+		// the plan acts on the trees, so they can't be FSIFT files
+		for side := 0; side < 2; side++ {
+			if info, err := os.Stat(self.Roots[side][0]); err != nil || !info.IsDir() {
+				self.fatal("The roots of a sync plan must be directories: ", self.Roots[side][0])
+			}
+		}
+		self.Plain = true
+	}
+	// deduplicating acts on the duplicate groups
+	if self.Dedupe != "" || self.KeepRule != "" || self.DryRun || self.IgnorePerms || self.DedupeLog != "" {
+		if self.Dedupe != "hardlink" && self.Dedupe != "reflink" {
+			self.fatal("--dedupe must be hardlink or reflink")
+		}
+		if self.KeepRule == "" {
+			self.KeepRule = "first"
+		}
+		if !keepRules[self.KeepRule] {
+			self.fatal("--keep must be first, oldest, newest or shortest")
+		}
+		if self.DryRun {
+			self.dedupeStats.name = "Would dedupe:"
+		}
+		self.Duplicates = true
+	}
+	// --fdupes output is a plain list of duplicate groups
+	if self.Fdupes {
+		self.Duplicates = true
+		self.Plain = true
+	}
+	// duplicates are found by size and content unless a key was given
+	if self.Duplicates && self.KeyCols.cols == nil {
+		self.KeyCols.cols = []Column{ColSize}
+		if !self.AddMd5 && !self.AddSha1 && !self.AddSha256 && !self.AddSha512 {
+			self.KeyCols.cols = append(self.KeyCols.cols, ColMd5)
+		}
+	}
 	// make sure output defaults are set if no options were given
 	self.UpdateColumnsCmdlineArg(&self.KeyCols, 0, "+")
 
@@ -270,20 +382,51 @@ func (self *Context) adjustCmdlineOptions() {
 		self.UpdateColumnsCmdlineArg(&self.KeyCols, 0, "+sha512")
 	}
 
+	// a three-way merge pairs entries by identity, defaulting to path, and
+	// adds the merge column by default
+	if self.Merge || self.MergeActions != "" {
+		if nSides != 3 {
+			self.fatal("A three-way merge needs three sides: base : B : C")
+		}
+		self.Merge = true
+		if !self.usesIdentity() {
+			self.IdentityCols.cols = []Column{ColPath}
+		}
+		self.UpdateColumnsCmdlineArg(&self.OutCols, 0, "+merge")
+	}
+
+	// classifying changes adds the change column by default
+	if self.usesIdentity() && !self.Merge {
+		self.UpdateColumnsCmdlineArg(&self.OutCols, 0, "+change")
+	}
+
 	// add postfilters to implement any --membership codes
 	var filts []*Filter
-	for _, c := range self.MembershipFilt {
-		switch c {
-		case 'L':
-			filts = append(filts, &Filter{op: opEq, column: ColMembership, value: "<!"})
-		case 'R':
-			filts = append(filts, &Filter{op: opEq, column: ColMembership, value: ">!"})
-		case 'l':
-			filts = append(filts, &Filter{op: opEq, column: ColMembership, value: "<="})
-		case 'r':
-			filts = append(filts, &Filter{op: opEq, column: ColMembership, value: ">="})
-		default:
-			self.fatal("--membership filter codes must be one or more of [lrLR]")
+	if nSides > 2 {
+		// with more than two sides, the codes are patterns of side letters
+		if self.MembershipFilt != "" {
+			if filts, err = self.sidePatternFilters(self.MembershipFilt); err != nil {
+				self.fatal(err)
+			}
+		}
+	} else {
+		for _, c := range self.MembershipFilt {
+			switch c {
+			case 'L':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: "<!"})
+			case 'R':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: ">!"})
+			case 'l':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: "<="})
+			case 'r':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: ">="})
+			case 'o':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: "<~"})
+			case 'n':
+				filts = append(filts, &Filter{op: opEq, column: ColMembership, value: ">~"})
+			default:
+				self.fatal("--membership filter codes must be one or more of [lrLRon]")
+			}
 		}
 	}
 	// all of the membership filters get ORed together
@@ -292,12 +435,116 @@ func (self *Context) adjustCmdlineOptions() {
 	}
 	self.PostFilterArgs = append(self.PostFilterArgs, filts...)
 
+	// --grep only outputs files with matching lines
+	if self.Grep != nil {
+		self.PostFilterArgs = append(self.PostFilterArgs, &Filter{op: opLessEq, column: ColGrepCount, value: int64(0), not: true})
+	}
+
 	// determine the set of all columns to calculate
 	self.calcNeededCols()
-	if hasRight {
+	if nSides > 1 {
 		self.neededCols[ColMatched] = true
 		self.neededCols[ColSide] = true
 	}
+	if self.needsCol(ColEntJump) {
+		self.neededCols[ColEntropy] = true
+	}
+	// grouped output defaults to counting the entries and their sizes
+	if self.grouping() {
+		if self.sideBySide() {
+			self.fatal("Grouped output can't be combined with side-by-side output")
+		}
+		if len(self.Aggregates) == 0 {
+			self.Aggregates, _ = ParseAggregates("count,sum:size")
+		}
+		if self.groupSort, err = self.parseGroupSort(); err != nil {
+			self.fatal(err)
+		}
+		for _, agg := range self.Aggregates {
+			if agg.kind != aggCount {
+				self.neededCols[agg.col] = true
+			}
+		}
+	}
+	// a limit would cut the groups that sync plans and duplicates act on
+	if self.Limit > 0 && (self.SyncPlan != "" || self.Duplicates) {
+		self.fatal("--limit can't be used with --sync-plan, --duplicates or --dedupe")
+	}
+	// limits may apply to groups of entries
+	if len(self.LimitCols.cols) > 0 {
+		if self.Limit <= 0 {
+			self.fatal("--limit-by needs a --limit")
+		}
+		for _, col := range self.LimitCols.cols {
+			self.neededCols[col] = true
+		}
+	}
+	// verifying content needs to read the files
+	if self.VerifyContent {
+		self.neededCols[ColAbsPath] = true
+	}
+	// duplicate output replaces the entry lines
+	if self.Duplicates {
+		if self.JsonOut || self.grouping() || self.sideBySide() {
+			self.fatal("Duplicate output can't be combined with JSON, grouped or side-by-side output")
+		}
+		self.neededCols[ColSize] = true
+		if self.Fdupes || self.Dedupe != "" {
+			self.neededCols[ColAbsPath] = true
+		}
+		if self.KeepRule == "oldest" || self.KeepRule == "newest" {
+			self.neededCols[ColMtime] = true
+		}
+	}
+	// histograms bucket the output files by size or age
+	if self.Histogram != nil {
+		self.neededCols[self.Histogram.column()] = true
+	}
+	// sync plans are made from the changes of each entry
+	if self.SyncPlan != "" {
+		if self.JsonOut || self.grouping() || self.sideBySide() || self.Duplicates || self.Merge {
+			self.fatal("A sync plan can't be combined with JSON, grouped, side-by-side, duplicate or merge output")
+		}
+		for _, col := range []Column{ColChange, ColChanged, ColModestr, ColMtime} {
+			self.neededCols[col] = true
+		}
+	}
+	// side-by-side output pairs entries by identity and shows their change
+	if self.sideBySide() {
+		if self.JsonOut {
+			self.fatal("Side-by-side output can't be combined with JSON output")
+		}
+		self.neededCols[ColChange] = true
+	}
+	// change columns need an identity key; default to path
+	if (self.needsCol(ColChange) || self.needsCol(ColChanged)) && !self.usesIdentity() {
+		self.IdentityCols.cols = []Column{ColPath}
+		self.neededCols[ColPath] = true
+	}
+	if self.usesIdentity() {
+		self.neededCols[ColSide] = true
+	}
+	// move detection needs the content of unmatched files
+	if self.needsCol(ColOtherPath) {
+		self.DetectMoves = true
+	}
+	// moves and changes pair entries on the left side with the right side
+	if nSides != 2 && (self.DetectMoves || self.usesIdentity() && !self.Merge || self.needsCol(ColChange) || self.needsCol(ColChanged)) {
+		self.fatal("Move detection, change classification and side-by-side output only work with two sides")
+	}
+	if self.DetectMoves {
+		self.moveDigest = self.chooseMoveDigest()
+		self.neededCols[self.moveDigest] = true
+		self.neededCols[ColSize] = true
+		self.neededCols[ColMatched] = true
+		self.neededCols[ColSide] = true
+	}
+	if !self.Merge && (self.needsCol(ColMerge) || self.needsCol(ColMergeAct)) {
+		self.fatal("The merge and mergeaction columns require --merge")
+	}
+	if self.Grep == nil && (self.needsCol(ColGrepCount) || self.needsCol(ColGrepLine)) {
+		self.fatal("The grepcount and grepline columns require a --grep pattern")
+	}
 
 	// compile filter lists into trees
 	self.postFilter, err = compileFilter(self.PostFilterArgs)
@@ -312,13 +559,20 @@ func (self *Context) adjustCmdlineOptions() {
 	if err != nil {
 		self.fatal("Error compiling prune filter args:", err)
 	}
+	// relative dates in filters are relative to the start of the run, and sets
+	// for "in" filters are loaded now
+	for _, filt := range []*Filter{self.postFilter, self.preFilter, self.pruneFilter} {
+		if err = self.resolveFilterValues(filt); err != nil {
+			self.fatal("Error loading filter values:", err)
+		}
+	}
 
 	// reset current side flag in preparation for run
-	self.CurSide = false
+	self.CurSide = 0
 
 	// if no roots given, default to just "."
-	if !hasLeft && !hasRight {
-		self.Roots[false] = append(self.Roots[false], ".")
+	if nSides == 0 {
+		self.Roots[0] = append(self.Roots[0], ".")
 	}
 	// create the output file if specified
 	if self.OutputPath != "" {
@@ -335,24 +589,29 @@ func (self *Context) adjustCmdlineOptions() {
 // Returns zero on success, nonzero if any errors occurred during the run.
 func (self *Context) Run() int {
 	// finalize settings and output header
+	self.startTime = time.Now()
 	self.adjustCmdlineOptions()
 	self.showHeader()
 
-	// scan roots on left then right side
-	for i := 0; i < 2; i++ {
-		for _, path := range self.Roots[i != 0] {
+	// scan roots on each side in turn
+	for side := 0; side < self.numSides(); side++ {
+		for _, path := range self.Roots[side] {
 			self.outTempf(0, "Processing root... '%s'", path)
-			self.CurSide = i != 0
+			self.CurSide = side
 			self.processRoot(path)
 		}
 	}
 
 	// if calculating matches, go do file matching
-	if self.needsCol(ColMatched) || self.needsCol(ColRedundancy) || self.needsCol(ColRedunIdx) {
+	if self.needsCol(ColMatched) || self.needsCol(ColRedundancy) || self.needsCol(ColRedunIdx) ||
+		self.needsCol(ColEntJump) || self.usesIdentity() {
 		self.analyzeMatches()
 	}
+	if self.MergeActions != "" {
+		self.writeMergeActions()
+	}
 
-	// do postfiltereing, and also dummy output pass to calc column widths
+	// do postfiltereing and any limit, then a dummy output pass to calc column widths
 	self.outTempf(0, "Filtering and formatting... %d", len(self.entries))
 	nCols := len(self.OutCols.cols)
 	widths := make([]int, nCols)
@@ -361,6 +620,10 @@ func (self *Context) Run() int {
 		widths[nCols-1] = -1
 	}
 	filtered := []fileEntry{} // entries that pass the postfilter
+	var limiter *entryLimiter // if limiting, keeps the entries to output
+	if self.Limit > 0 {
+		limiter = self.newEntryLimiter()
+	}
 	for _, e := range self.entries {
 		// check against postfilter
 		match, notNull := self.postFilter.filter(e)
@@ -368,7 +631,16 @@ func (self *Context) Run() int {
 		if !match {
 			continue
 		}
-		filtered = append(filtered, e)
+		if limiter != nil {
+			limiter.add(e)
+		} else {
+			filtered = append(filtered, e)
+		}
+	}
+	if limiter != nil {
+		filtered = limiter.result()
+	}
+	for _, e := range filtered {
 		// calculate output for all but last column to get max widths
 		for i := 0; i < nCols-1; i++ {
 			// plain0 and json don't get padded, also skip if only summary to speed things up
@@ -402,17 +674,19 @@ func (self *Context) Run() int {
 		indent = ""
 	}
 	var fields []string
-	if !self.SummaryOnly {
+	if self.SyncPlan != "" {
+		self.outputSyncPlan(filtered)
+	} else if self.Duplicates {
+		self.outputDuplicates(filtered, indent, separator)
+	} else if self.grouping() && !self.SummaryOnly {
+		self.outputGroups(filtered, self.groupSort, indent, separator)
+	} else if self.sideBySide() && !self.SummaryOnly {
+		self.outputSideBySide(filtered, indent, separator)
+	} else if !self.SummaryOnly {
 		// go through filtered entries output them
 		for j, e := range filtered {
 			if !self.JsonOut {
-				// update output stats
-				filePath, _ := e.getStringField(ColPath)
-				if !strings.HasSuffix(filePath, "/") {
-					self.outputStats.update(e.getBoolFieldOrFalse(ColSide), e.getNumericFieldOrZero(ColSize))
-				} else {
-					self.outputStats.update(e.getBoolFieldOrFalse(ColSide), 0)
-				}
+				self.updateOutputStats(e)
 				// format the output fields in this entry, padded to the max column width and output the line
 				fields = fields[:0]
 				for i, col := range self.OutCols.cols {
@@ -437,6 +711,16 @@ func (self *Context) Run() int {
 				}
 			}
 		}
+	} else if self.Histogram != nil {
+		// only the summary is shown; the histogram counts the files that would be output
+		for _, e := range filtered {
+			if !strings.HasSuffix(entryPath(e), "/") {
+				self.updateHistogram(e, e.getNumericFieldOrZero(ColSize))
+			}
+		}
+	}
+	if self.unverified {
+		self.onWarning("The content of files without an absolute path, such as those loaded from FSIFT files saved without the abspath column, was not verified")
 	}
 	// show summary info
 	self.showFooter()
@@ -0,0 +1,118 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// Add a value to the set used by an "in" filter on the given column. Values
+// for numeric columns are normalized so they compare the same way as the
+// entry values, for example "1K" is stored as "1000".
+func addSetValue(set map[string]bool, col Column, value string) error {
+	if col.isNumeric() {
+		n, relTime, err := parseFilterValue(col, value)
+		if err != nil {
+			return err
+		}
+		if relTime {
+			return fmt.Errorf("Relative times are not allowed in sets: '%s'", value)
+		}
+		value = strconv.FormatInt(n.(int64), 10)
+	} else if col == ColMtime {
+		if tm, ok := parseDateLiteral(value); ok {
+			value = timeToMtime(tm, nil)
+		}
+	}
+	set[value] = true
+	return nil
+}
+
+// Split the contents of a plain list file into values. Values are separated
+// by null characters if there are any, otherwise by line breaks. Empty values
+// are skipped.
+func splitSetFile(data []byte) [][]byte {
+	sep := []byte("\n")
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte("\x00")
+	}
+	var out [][]byte
+	for _, item := range bytes.Split(data, sep) {
+		if sep[0] == '\n' {
+			item = bytes.TrimSuffix(item, []byte("\r"))
+		}
+		if len(item) > 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Load the set of values for an "in" filter from a file. If the file is a
+// FSIFT file, the values are taken from the filter's column of each entry.
+// Otherwise, it is a list of values.
+func (self *Context) loadFilterSet(col Column, path string) (map[string]bool, error) {
+	set := map[string]bool{}
+	isSifter, err := detectSifterFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isSifter {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		entries, found := 0, false
+		err = self.scanSifterFile(file, func(entry fileEntry) {
+			entries++
+			var value string
+			var ok bool
+			if col.isNumeric() {
+				var n int64
+				n, ok = entry.getNumericField(col)
+				value = strconv.FormatInt(n, 10)
+			} else {
+				value, ok = entry.getStringField(col)
+			}
+			if ok {
+				set[value] = true
+				found = true
+			}
+		})
+		if err == nil && entries > 0 && !found {
+			err = fmt.Errorf("No values for column '%s' in FSIFT file '%s'", col, path)
+		}
+		return set, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range splitSetFile(data) {
+		if err = addSetValue(set, col, string(item)); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,138 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_addSetValue(t *testing.T) {
+	var tests = []struct {
+		col     Column
+		value   string
+		want    string
+		wantErr string
+	}{
+		{ColPath, "a b", "a b", ""},
+		{ColSize, "1K", "1000", ""},
+		{ColSize, "x", "", "strconv.ParseInt"},
+		{ColMstamp, "-1d", "", "Relative times are not allowed in sets"},
+		{ColMtime, "2024-01-01", "2024-01-01T00:00:00Z", ""},
+	}
+	for _, test := range tests {
+		set := map[string]bool{}
+		err := addSetValue(set, test.col, test.value)
+		if test.wantErr == "" {
+			checkValErr1(t, map[string]bool{test.want: true}, set, "", err)
+		} else {
+			checkValErr1(t, nil, nil, test.wantErr, err)
+		}
+	}
+}
+
+func Test_splitSetFile(t *testing.T) {
+	var tests = []struct {
+		data string
+		want []string
+	}{
+		{"", nil},
+		{"a\nb c\r\n\nd", []string{"a", "b c", "d"}},
+		{"a\nb\x00c\x00", []string{"a\nb", "c"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, item := range splitSetFile([]byte(test.data)) {
+			got = append(got, string(item))
+		}
+		checkVal(t, test.want, got)
+	}
+}
+
+func Test_Context_loadFilterSet(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "sifter_unittest_")
+	if err != nil {
+		t.Error("Couln't create temp dir for unit test")
+		return
+	}
+	defer func() { os.RemoveAll(dirPath) }()
+	listPath := filepath.Join(dirPath, "list.txt")
+	ioutil.WriteFile(listPath, []byte("a/x.txt\nb\n"), 0644)
+	nulPath := filepath.Join(dirPath, "list0")
+	ioutil.WriteFile(nulPath, []byte("2K\x005\x00"), 0644)
+	siftPath := filepath.Join(dirPath, "t.FSIFT")
+	ioutil.WriteFile(siftPath, []byte(sifterFileHeader+"\n| Columns: size,path\n  10  a/x.txt\n  20  b\\ c\n"), 0644)
+
+	var tests = []struct {
+		col     Column
+		path    string
+		want    map[string]bool
+		wantErr string
+	}{
+		{ColPath, listPath, map[string]bool{"a/x.txt": true, "b": true}, ""},
+		{ColSize, nulPath, map[string]bool{"2000": true, "5": true}, ""},
+		{ColSize, listPath, nil, "strconv.ParseInt"},
+		{ColPath, siftPath, map[string]bool{"a/x.txt": true, "b c": true}, ""},
+		{ColSize, siftPath, map[string]bool{"10": true, "20": true}, ""},
+		{ColBase, siftPath, map[string]bool{"x.txt": true, "b c": true}, ""}, // derived column
+		{ColUser, siftPath, nil, "No values for column 'user' in FSIFT file"},
+		{ColPath, filepath.Join(dirPath, "none"), nil, "open "},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		got, err := ctx.loadFilterSet(test.col, test.path)
+		if test.wantErr == "" {
+			checkValErr1(t, test.want, got, "", err)
+		} else {
+			checkValErr1(t, nil, nil, test.wantErr, err)
+		}
+	}
+
+	// filters using sets from files and inline lists
+	entry := fileEntry{ColPath: "a/x.txt", ColSize: int64(2000)}
+	var filtTests = []struct {
+		arg       string
+		expr      bool
+		wantMatch bool
+	}{
+		{"path in @" + listPath, false, true},
+		{"path !in @" + listPath, false, false},
+		{"size in 1,2K,3", false, true},
+		{"size in @" + siftPath, false, false},
+		{"base in x.txt,y.txt", false, true},
+		{"path in '@" + listPath + "' and not size in @" + nulPath, true, false},
+	}
+	for _, test := range filtTests {
+		var filt *Filter
+		if test.expr {
+			filt, err = ParseFilterExpr(test.arg)
+		} else {
+			filt, err = ParseFilter(test.arg)
+		}
+		checkValErr1(t, nil, nil, "", err)
+		ctx := NewContext()
+		err = ctx.resolveFilterValues(filt)
+		checkValErr1(t, nil, nil, "", err)
+		match, ok := filt.filter(entry)
+		checkVal(t, test.wantMatch, match)
+		checkVal(t, true, ok)
+	}
+}
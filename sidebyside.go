@@ -0,0 +1,151 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A line of side-by-side output: the left and right entries with the same
+// identity. Either may be nil if there is no such entry.
+type sideBySideRow [2]fileEntry
+
+// Return true if side-by-side output was requested.
+func (self *Context) sideBySide() bool {
+	return len(self.SideCols.cols) > 0
+}
+
+// Return the columns shown once for each side-by-side row: the change
+// column, then the identity columns.
+func (self *Context) sharedCols() []Column {
+	return append([]Column{ColChange}, self.IdentityCols.cols...)
+}
+
+// Return the names of the side-by-side output columns, like
+// "change,path,L:size,R:size".
+func (self *Context) sideBySideColumnNames() string {
+	names := []string{formatColumnNames(self.sharedCols())}
+	for _, col := range self.SideCols.cols {
+		names = append(names, "L:"+col.String(), "R:"+col.String())
+	}
+	return strings.Join(names, ",")
+}
+
+// Return a key for the identity of an entry, from the entry to compare in
+// its place (with any path and id maps applied, and file name columns folded
+// if FoldKeys is set). Entries have the same key if they compare equal by the
+// identity columns. Null values are flagged like null compares.
+func (self *Context) identityKey(key fileEntry) string {
+	id := ""
+	for _, col := range self.IdentityCols.cols {
+		val, ok := key.getField(col)
+		self.checkNullCompare(ok)
+		id += fmt.Sprintf("%v\x00%v\x00", ok, val)
+	}
+	return id
+}
+
+// Pair up entries with the same identity on each side into rows, comparing
+// the identities with any path and id maps applied. Rows are in order of
+// their first entry in the list. If there are several entries with the same
+// identity on a side, they are paired in order.
+func (self *Context) pairRows(entries []fileEntry) []sideBySideRow {
+	keys := self.mapKeyEntries(entries)
+	if keys == nil {
+		keys = entries
+	}
+	var rows []sideBySideRow
+	open := map[string]*[2][]int{} // rows with an empty place on each side, by identity
+	for i, entry := range entries {
+		side := sideIndex(entry)
+		id := self.identityKey(keys[i])
+		waiting := open[id]
+		if waiting == nil {
+			waiting = &[2][]int{}
+			open[id] = waiting
+		}
+		if len(waiting[side]) > 0 {
+			r := waiting[side][0]
+			rows[r][side] = entry
+			waiting[side] = waiting[side][1:]
+		} else {
+			var row sideBySideRow
+			row[side] = entry
+			rows = append(rows, row)
+			waiting[1-side] = append(waiting[1-side], len(rows)-1)
+		}
+	}
+	return rows
+}
+
+// Format the fields of a side-by-side row. The shared columns come from
+// whichever entry is present. The per-side columns get the value from each
+// side; a missing entry shows as a null value. If both entries are present
+// and their values differ, both values are marked with '*' characters.
+func (self *Context) formatRow(row sideBySideRow) []string {
+	var fields []string
+	shared := row[0]
+	if shared == nil {
+		shared = row[1]
+	}
+	nSide := len(self.SideCols.cols)
+	for i, col := range self.sharedCols() {
+		fields = append(fields, shared.formatField(self, col, -1, self.Plain0 || nSide == 0 && i == len(self.sharedCols())-1))
+	}
+	for i, col := range self.SideCols.cols {
+		var vals [2]string
+		for side, entry := range row {
+			if entry == nil {
+				vals[side] = escapeField("", false, false)
+			} else {
+				vals[side] = entry.formatField(self, col, -1, self.Plain0 || side == 1 && i == nSide-1)
+			}
+		}
+		if row[0] != nil && row[1] != nil && vals[0] != vals[1] {
+			vals[0], vals[1] = "*"+vals[0]+"*", "*"+vals[1]+"*"
+		}
+		fields = append(fields, vals[0], vals[1])
+	}
+	return fields
+}
+
+// Output the filtered entries as side-by-side rows, padded to the max column
+// widths, and update the output statistics.
+func (self *Context) outputSideBySide(entries []fileEntry, indent, separator string) {
+	var numeric []bool
+	for _, col := range self.sharedCols() {
+		numeric = append(numeric, col.isNumeric())
+	}
+	for _, col := range self.SideCols.cols {
+		numeric = append(numeric, col.isNumeric(), col.isNumeric())
+	}
+	var lines [][]string
+	for _, row := range self.pairRows(entries) {
+		// update output stats
+		for _, e := range row {
+			if e == nil {
+				continue
+			}
+			self.updateOutputStats(e)
+		}
+		lines = append(lines, self.formatRow(row))
+	}
+	self.outputTable(lines, numeric, indent, separator)
+}
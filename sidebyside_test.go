@@ -0,0 +1,60 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_Context_pairRows(t *testing.T) {
+	ctx := NewContext()
+	ctx.IdentityCols = ColSelector{cols: []Column{ColPath}}
+	a0 := fileEntry{ColPath: "a", ColSide: int64(0)}
+	b1 := fileEntry{ColPath: "b", ColSide: int64(1)}
+	a1 := fileEntry{ColPath: "a", ColSide: int64(1)}
+	c0 := fileEntry{ColPath: "c", ColSide: int64(0)}
+	c0b := fileEntry{ColPath: "c", ColSide: int64(0)}
+	c1 := fileEntry{ColPath: "c", ColSide: int64(1)}
+	rows := ctx.pairRows([]fileEntry{a0, b1, a1, c0, c0b, c1})
+	checkVal(t, []sideBySideRow{{a0, a1}, {nil, b1}, {c0, c1}, {c0b, nil}}, rows)
+}
+
+func Test_Context_pairRows_maps(t *testing.T) {
+	ctx := NewContext()
+	ctx.IdentityCols = ColSelector{cols: []Column{ColPath}}
+	ctx.FoldKeys = true
+	pathMap, _ := ParsePathMap("home/=>users/")
+	ctx.LeftPathMaps = []PathMap{pathMap}
+	left := fileEntry{ColPath: "home/Alice/f", ColSide: int64(0)}
+	other := fileEntry{ColPath: "users/bob/g", ColSide: int64(0)}
+	right := fileEntry{ColPath: "users/alice/f", ColSide: int64(1)}
+	rows := ctx.pairRows([]fileEntry{left, other, right})
+	checkVal(t, []sideBySideRow{{left, right}, {other, nil}}, rows)
+}
+
+func Test_Context_formatRow(t *testing.T) {
+	ctx := NewContext()
+	ctx.IdentityCols = ColSelector{cols: []Column{ColPath}}
+	ctx.SideCols = ColSelector{cols: []Column{ColSize, ColUser}}
+	left := fileEntry{ColPath: "a", ColChange: "modified", ColSize: int64(1), ColUser: "joe"}
+	right := fileEntry{ColPath: "a", ColChange: "modified", ColSize: int64(2), ColUser: "joe"}
+	checkVal(t, []string{"modified", "a", "*1*", "*2*", "joe", "joe"}, ctx.formatRow(sideBySideRow{left, right}))
+	checkVal(t, []string{"modified", "a", `\~`, "2", `\~`, "joe"}, ctx.formatRow(sideBySideRow{nil, right}))
+	checkVal(t, "change,path,L:size,R:size,L:user,R:user", ctx.sideBySideColumnNames())
+}
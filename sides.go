@@ -0,0 +1,97 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The maximum number of sides, each named by a letter
+const maxSides = 26
+
+// Return the side of an entry's root: 0 for the left (first) side, 1 for the
+// right (second) side, and so on.
+func sideIndex(entry fileEntry) int {
+	return int(entry.getNumericFieldOrZero(ColSide))
+}
+
+// Return the number of sides in this run: one more than the last side that
+// has any roots, or zero if there are no roots.
+func (self *Context) numSides() int {
+	n := 0
+	for side, roots := range self.Roots {
+		if len(roots) > 0 && side >= n {
+			n = side + 1
+		}
+	}
+	return n
+}
+
+// Return the name of a side: 'L' or 'R' if there are two sides, otherwise
+// a letter starting with 'A'.
+func (self *Context) sideName(side int) string {
+	if self.numSides() <= 2 {
+		return string("LR"[side])
+	}
+	return string(rune('A' + side))
+}
+
+// Format the membership of a group of matching entries on more than two
+// sides, given the count of entries on each side: the letter of each side
+// that holds an entry, or '-' if it doesn't, like "AB-".
+func formatSides(counts []int) string {
+	buf := make([]byte, len(counts))
+	for side, n := range counts {
+		buf[side] = '-'
+		if n > 0 {
+			buf[side] = byte('A' + side)
+		}
+	}
+	return string(buf)
+}
+
+// Create the postfilters for --membership when there are more than two
+// sides. The codes are comma-separated patterns with a character for each
+// side: the side's letter if it must hold the file, '-' if it must not, or
+// '?' for either. For example, "A?-" selects files on A that are missing
+// from C. The --diff shortcut "LR" selects files missing from any side.
+func (self *Context) sidePatternFilters(codes string) ([]*Filter, error) {
+	if codes == "LR" {
+		return []*Filter{{op: opEq, column: ColMatched, value: int64(0)}}, nil
+	}
+	var filts []*Filter
+	nSides := self.numSides()
+	for _, pattern := range strings.Split(codes, ",") {
+		if len(pattern) != nSides {
+			return nil, fmt.Errorf("--membership pattern '%s' must have a character for each of the %d sides", pattern, nSides)
+		}
+		for side, c := range pattern {
+			if c != '-' && c != '?' && c != rune('A'+side) {
+				return nil, fmt.Errorf("--membership pattern '%s' must have '%c', '-' or '?' at position %d", pattern, 'A'+side, side+1)
+			}
+		}
+		filt, err := newCompareFilter("membership", "*=", pattern)
+		if err != nil {
+			return nil, err
+		}
+		filts = append(filts, filt)
+	}
+	return filts, nil
+}
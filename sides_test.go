@@ -0,0 +1,106 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_Context_numSides(t *testing.T) {
+	ctx := NewContext()
+	checkVal(t, 0, ctx.numSides())
+	ctx.Roots[1] = []string{"r"}
+	checkVal(t, 2, ctx.numSides())
+	checkVal(t, "R", ctx.sideName(1))
+	ctx.Roots[2] = []string{"r"}
+	ctx.Roots[3] = nil
+	checkVal(t, 3, ctx.numSides())
+	checkVal(t, "C", ctx.sideName(2))
+}
+
+func Test_formatSides(t *testing.T) {
+	checkVal(t, "A-C", formatSides([]int{1, 0, 2}))
+	checkVal(t, "---", formatSides([]int{0, 0, 0}))
+}
+
+func Test_Context_sidePatternFilters(t *testing.T) {
+	ctx := NewContext()
+	ctx.Roots = map[int][]string{0: {"a"}, 1: {"b"}, 2: {"c"}}
+	var tests = []struct {
+		codes string
+		want  []bool // results for membership "AB-", "A-C", "ABC"
+		err   string
+	}{
+		{"A?-", []bool{true, false, false}, ""},
+		{"ABC,-?-", []bool{false, false, true}, ""},
+		{"LR", []bool{true, true, false}, ""},
+		{"AB", nil, "--membership pattern 'AB' must have a character for each of the 3 sides"},
+		{"AC?", nil, "--membership pattern 'AC?' must have 'B', '-' or '?' at position 2"},
+	}
+	for _, test := range tests {
+		filts, err := ctx.sidePatternFilters(test.codes)
+		var got []bool
+		for _, m := range []string{"AB-", "A-C", "ABC"} {
+			entry := fileEntry{ColMembership: m, ColMatched: int64(0)}
+			if m == "ABC" {
+				entry[ColMatched] = int64(1)
+			}
+			match := false
+			for _, filt := range filts {
+				ok, _ := filt.filter(entry)
+				match = match || ok
+			}
+			if filts != nil {
+				got = append(got, match)
+			}
+		}
+		checkValErr1(t, test.want, got, test.err, err)
+	}
+}
+
+func Test_Context_analyzeMatchesSides(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols = ColSelector{cols: []Column{ColPath}}
+	ctx.Roots = map[int][]string{0: {"a"}, 1: {"b"}, 2: {"c"}}
+	ctx.neededCols = map[Column]bool{ColRedundancy: true}
+	ctx.entries = []fileEntry{
+		{ColPath: "x", ColSide: int64(0)},
+		{ColPath: "x", ColSide: int64(1)},
+		{ColPath: "x", ColSide: int64(2)},
+		{ColPath: "x", ColSide: int64(2)},
+		{ColPath: "y", ColSide: int64(0)},
+		{ColPath: "y", ColSide: int64(2)},
+		{ColPath: "z", ColSide: int64(1)},
+	}
+	ctx.analyzeMatches()
+	var members []string
+	var matched []bool
+	var redun []int64
+	for _, entry := range ctx.entries {
+		m, _ := entry.getStringField(ColMembership)
+		members = append(members, m)
+		matched = append(matched, entry.getBoolFieldOrFalse(ColMatched))
+		redun = append(redun, entry.getNumericFieldOrZero(ColRedundancy))
+	}
+	checkVal(t, []string{"ABC", "ABC", "ABC", "ABC", "A-C", "A-C", "-B-"}, members)
+	checkVal(t, []bool{true, true, true, true, false, false, false}, matched)
+	checkVal(t, []int64{1, 1, 2, 2, 1, 1, 1}, redun)
+	count, _ := ctx.unmatchedStats.get(2)
+	checkVal(t, int64(1), count)
+}
@@ -0,0 +1,280 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of sync plan actions, in the order they're done
+const (
+	syncMkdir     = "mkdir"     // create a directory missing on the right
+	syncMove      = "move"      // move a file on the right to its path on the left
+	syncCopy      = "copy"      // copy a file missing on the right
+	syncOverwrite = "overwrite" // copy a file whose content differs on the right
+	syncMetadata  = "metadata"  // set the permissions and mtime of a file on the right
+	syncDelete    = "delete"    // delete a file missing on the left
+	syncRmdir     = "rmdir"     // delete a directory missing on the left
+)
+
+// The order of the sync plan actions
+var syncOrder = map[string]int{
+	syncMkdir: 0, syncMove: 1, syncCopy: 2, syncOverwrite: 3, syncMetadata: 4, syncDelete: 5, syncRmdir: 6,
+}
+
+// Formats of sync plans for --sync-plan
+var syncFormats = map[string]bool{"sh": true, "rsync": true, "json": true}
+
+// An action of a sync plan. Paths are relative to the roots.
+type syncAction struct {
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	From   string `json:"from,omitempty"`  // with move, the path on the right to move from
+	Mode   string `json:"mode,omitempty"`  // with metadata, the permissions in octal
+	Mtime  string `json:"mtime,omitempty"` // with metadata, the modification time
+}
+
+// A sync plan in JSON format
+type syncPlanJson struct {
+	Source      string       `json:"source"`
+	Destination string       `json:"destination"`
+	Actions     []syncAction `json:"actions"`
+}
+
+// Convert a mode string like "-rwxr-xr-x" to octal permissions like "755",
+// including any setuid, setgid and sticky bits.
+func modeStrToPerm(modeStr string) string {
+	if len(modeStr) < 9 {
+		return ""
+	}
+	var perm uint32
+	for i, c := range modeStr[len(modeStr)-9:] {
+		if c != '-' {
+			perm |= 1 << uint(8-i)
+		}
+	}
+	for _, c := range modeStr[:len(modeStr)-9] {
+		switch c {
+		case 'u':
+			perm |= 04000
+		case 'g':
+			perm |= 02000
+		case 't':
+			perm |= 01000
+		}
+	}
+	return fmt.Sprintf("%o", perm)
+}
+
+// Return true if a file that changed in the given key columns only needs
+// its metadata fixed: only its permissions or times changed, and a changed
+// time doesn't mean the content changed, because the key has a digest.
+func (self *Context) metadataOnly(changed string) bool {
+	for _, name := range strings.Split(changed, ",") {
+		switch name {
+		case "modestr":
+		case "mtime", "mstamp":
+			if !self.keyHasDigest() {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Make a sync plan to bring the right side in line with the left side from
+// the changes of the filtered entries: copy files only on the left,
+// overwrite modified files (or just fix their metadata), delete files only
+// on the right, and move files detected as moved. Directories are created
+// and deleted, but not otherwise changed; a directory is only deleted if
+// everything under it on the right is deleted or moved away, since filtered
+// out entries stay there. Missing parent directories of copied and moved
+// files are created. Actions are in the order they should be done, then in
+// path order (with directories deleted deepest first).
+func (self *Context) makeSyncPlan(entries []fileEntry) []syncAction {
+	// the directories on the right, and those the plan creates
+	dirs := map[string]bool{".": true}
+	for _, e := range self.entries {
+		if p := entryPath(e); sideIndex(e) == 1 && strings.HasSuffix(p, "/") {
+			dirs[strings.TrimSuffix(p, "/")] = true
+		}
+	}
+	var plan []syncAction
+	addParents := func(p string) {
+		for dir := path.Dir(p); !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			plan = append(plan, syncAction{Action: syncMkdir, Path: dir})
+		}
+	}
+	for _, e := range entries {
+		change, _ := e.getStringField(ColChange)
+		isDir := strings.HasSuffix(entryPath(e), "/")
+		p := strings.TrimSuffix(entryPath(e), "/")
+		if p == "." {
+			continue // the roots themselves
+		}
+		left := sideIndex(e) == 0
+		switch {
+		case left && change == changeRemoved && isDir:
+			if !dirs[p] {
+				addParents(p)
+				dirs[p] = true
+				plan = append(plan, syncAction{Action: syncMkdir, Path: p})
+			}
+		case left && change == changeRemoved:
+			addParents(p)
+			plan = append(plan, syncAction{Action: syncCopy, Path: p})
+		case left && change == changeMoved:
+			from, _ := e.getStringField(ColOtherPath)
+			addParents(p)
+			plan = append(plan, syncAction{Action: syncMove, Path: p, From: from})
+		case left && change == changeModified && !isDir:
+			changed, _ := e.getStringField(ColChanged)
+			if self.metadataOnly(changed) {
+				modeStr, _ := e.getStringField(ColModestr)
+				mtime, _ := e.getStringField(ColMtime)
+				plan = append(plan, syncAction{Action: syncMetadata, Path: p, Mode: modeStrToPerm(modeStr), Mtime: mtime})
+			} else {
+				plan = append(plan, syncAction{Action: syncOverwrite, Path: p})
+			}
+		case !left && change == changeAdded && isDir:
+			plan = append(plan, syncAction{Action: syncRmdir, Path: p})
+		case !left && change == changeAdded:
+			plan = append(plan, syncAction{Action: syncDelete, Path: p})
+		}
+	}
+	plan = self.keepNonEmptyDirs(plan)
+	sort.SliceStable(plan, func(i, j int) bool {
+		a1, a2 := plan[i], plan[j]
+		if o1, o2 := syncOrder[a1.Action], syncOrder[a2.Action]; o1 != o2 {
+			return o1 < o2
+		}
+		if a1.Action == syncRmdir {
+			return a1.Path > a2.Path
+		}
+		return a1.Path < a2.Path
+	})
+	return plan
+}
+
+// Remove the rmdir actions of directories on the right that would still
+// have something in them after the plan: an entry that isn't deleted or
+// moved away by the plan.
+func (self *Context) keepNonEmptyDirs(plan []syncAction) []syncAction {
+	gone := map[string]bool{}
+	for _, action := range plan {
+		switch action.Action {
+		case syncDelete, syncRmdir:
+			gone[action.Path] = true
+		case syncMove:
+			gone[action.From] = true
+		}
+	}
+	kept := map[string]bool{} // directories with something left in them
+	for _, e := range self.entries {
+		p := strings.TrimSuffix(entryPath(e), "/")
+		if sideIndex(e) != 1 || gone[p] {
+			continue
+		}
+		for dir := path.Dir(p); dir != "." && !kept[dir]; dir = path.Dir(dir) {
+			kept[dir] = true
+		}
+	}
+	var result []syncAction
+	for _, action := range plan {
+		if action.Action != syncRmdir || !kept[action.Path] {
+			result = append(result, action)
+		}
+	}
+	return result
+}
+
+// Quote a string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Return the root of a side for a sync plan, as an absolute path if possible.
+func (self *Context) syncRoot(side int) string {
+	root := self.Roots[side][0]
+	if abs, err := filepath.Abs(root); err == nil {
+		return abs
+	}
+	return root
+}
+
+// Output a sync plan for the filtered entries in the --sync-plan format: a
+// shell script, a list of the paths to transfer for rsync --files-from, or
+// a JSON object.
+func (self *Context) outputSyncPlan(entries []fileEntry) {
+	plan := self.makeSyncPlan(entries)
+	src, dst := self.syncRoot(0), self.syncRoot(1)
+	switch self.SyncPlan {
+	case "rsync":
+		// rsync can't delete listed files
+		for _, action := range plan {
+			if action.Action != syncDelete && action.Action != syncRmdir {
+				self.outf(-1, "%s", action.Path)
+			}
+		}
+	case "json":
+		if plan == nil {
+			plan = []syncAction{}
+		}
+		json, err := json.MarshalIndent(syncPlanJson{src, dst, plan}, "", "    ")
+		if err != nil {
+			self.onError("Error encoding JSON sync plan: ", err)
+			return
+		}
+		for _, line := range strings.Split(string(json), "\n") {
+			self.outf(-1, "%s", line)
+		}
+	default:
+		self.outf(-1, "#!/bin/sh")
+		self.outf(-1, "# Sync plan from File Sifter: make DST like SRC")
+		self.outf(-1, "set -e")
+		self.outf(-1, "SRC=%s", shellQuote(src))
+		self.outf(-1, "DST=%s", shellQuote(dst))
+		for _, action := range plan {
+			s, d := `"$SRC"/`+shellQuote(action.Path), `"$DST"/`+shellQuote(action.Path)
+			switch action.Action {
+			case syncMkdir:
+				self.outf(-1, "mkdir -p %s", d)
+			case syncMove:
+				self.outf(-1, "mv %s %s", `"$DST"/`+shellQuote(action.From), d)
+			case syncCopy, syncOverwrite:
+				self.outf(-1, "cp -p %s %s", s, d)
+			case syncMetadata:
+				self.outf(-1, "chmod %s %s", action.Mode, d)
+				self.outf(-1, "touch -d %s %s", shellQuote(action.Mtime), d)
+			case syncDelete:
+				self.outf(-1, "rm -f %s", d)
+			case syncRmdir:
+				self.outf(-1, "rmdir %s", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"testing"
+)
+
+func Test_modeStrToPerm(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"-rw-r--r--", "644"},
+		{"drwxr-x---", "750"},
+		{"urwxr-xr-x", "4755"},
+		{"dgtrwxrwxrwx", "3777"},
+		{"----------", "0"},
+		{"rw", ""},
+	}
+	for _, test := range tests {
+		checkVal(t, test.want, modeStrToPerm(test.input))
+	}
+}
+
+func Test_Context_metadataOnly(t *testing.T) {
+	var tests = []struct {
+		keyCols []Column
+		changed string
+		want    bool
+	}{
+		{[]Column{ColPath, ColSize, ColMtime, ColModestr}, "modestr", true},
+		{[]Column{ColPath, ColSize, ColMtime, ColModestr}, "mtime", false},
+		{[]Column{ColPath, ColSize, ColMtime, ColMd5}, "mtime", true},
+		{[]Column{ColPath, ColSize, ColMtime, ColMd5}, "mtime,md5", false},
+		{[]Column{ColPath, ColSize, ColUser}, "user", false},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.KeyCols.cols = test.keyCols
+		checkVal(t, test.want, ctx.metadataOnly(test.changed))
+	}
+}
+
+func Test_Context_makeSyncPlan(t *testing.T) {
+	ctx := NewContext()
+	ctx.KeyCols.cols = []Column{ColPath, ColSize, ColMtime, ColModestr}
+	entry := func(side int, path, change, changed string) fileEntry {
+		e := fileEntry{ColPath: path, ColSide: int64(side), ColChange: change, ColChanged: changed,
+			ColModestr: "-rwxr-xr-x", ColMtime: "2017-01-01T00:00:00Z"}
+		if change == changeMoved {
+			e[ColOtherPath] = "was/" + path
+		}
+		return e
+	}
+	ctx.entries = []fileEntry{
+		entry(0, "./", changeUnchanged, ""), entry(1, "./", changeUnchanged, ""),
+		entry(0, "a/b/c", changeRemoved, ""), entry(0, "a/", changeRemoved, ""),
+		entry(0, "e/", changeRemoved, ""), entry(1, "e2/", changeUnchanged, ""),
+		entry(0, "e2/f", changeRemoved, ""),
+		entry(0, "mod", changeModified, "size,mtime"), entry(1, "mod", changeModified, "size,mtime"),
+		entry(0, "meta", changeModified, "modestr"), entry(1, "meta", changeModified, "modestr"),
+		entry(0, "same", changeUnchanged, ""), entry(1, "same", changeUnchanged, ""),
+		entry(0, "x/moved", changeMoved, ""), entry(1, "was/x/moved", changeMoved, ""),
+		entry(1, "old/", changeAdded, ""), entry(1, "old/sub/", changeAdded, ""),
+		entry(1, "old/sub/gone", changeAdded, ""), entry(1, "dir/", changeModified, "mtime"),
+		entry(1, "part/", changeAdded, ""), entry(1, "part/shown", changeAdded, ""),
+		entry(1, "part/hidden", changeAdded, ""),
+	}
+	checkVal(t, []syncAction{
+		{Action: "mkdir", Path: "a"},
+		{Action: "mkdir", Path: "a/b"},
+		{Action: "mkdir", Path: "e"},
+		{Action: "mkdir", Path: "x"},
+		{Action: "move", Path: "x/moved", From: "was/x/moved"},
+		{Action: "copy", Path: "a/b/c"},
+		{Action: "copy", Path: "e2/f"},
+		{Action: "overwrite", Path: "mod"},
+		{Action: "metadata", Path: "meta", Mode: "755", Mtime: "2017-01-01T00:00:00Z"},
+		{Action: "delete", Path: "old/sub/gone"},
+		{Action: "delete", Path: "part/shown"},
+		{Action: "rmdir", Path: "old/sub"},
+		{Action: "rmdir", Path: "old"},
+	}, ctx.makeSyncPlan(ctx.entries[:len(ctx.entries)-1]))
+}
+
+func Test_shellQuote(t *testing.T) {
+	checkVal(t, `'a b'`, shellQuote("a b"))
+	checkVal(t, `'it'\''s'`, shellQuote("it's"))
+	checkVal(t, `'$x'`, shellQuote("$x"))
+}
@@ -0,0 +1,181 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Whether pairs of files have the same content, by their paths in sorted order
+type contentCache map[[2]string]bool
+
+// Return true if the files at two paths have the same content: they are the
+// same file, or they have the same size and the same bytes. Results are
+// remembered, so each pair of files is only compared once in a run.
+func (self *Context) sameFileContent(path1, path2 string) (bool, error) {
+	pair := [2]string{path1, path2}
+	if path2 < path1 {
+		pair = [2]string{path2, path1}
+	}
+	if same, ok := self.verified[pair]; ok {
+		return same, nil
+	}
+	info1, err := os.Stat(path1)
+	if err != nil {
+		return false, err
+	}
+	info2, err := os.Stat(path2)
+	if err != nil {
+		return false, err
+	}
+	same := os.SameFile(info1, info2)
+	if !same && info1.Size() == info2.Size() {
+		if same, err = sameContent(path1, path2); err != nil {
+			return false, err
+		}
+	}
+	if self.verified == nil {
+		self.verified = contentCache{}
+	}
+	self.verified[pair] = same
+	return same, nil
+}
+
+// For --verify-content, split each match group found by findMatchGroups into
+// groups of files with the same content, comparing the files byte for byte.
+// The entries (and keys, if not nil) are reordered so each group is still
+// contiguous, and groups gets the new group IDs.
+func (self *Context) verifyMatchGroups(entries, keys []fileEntry, groups []int) {
+	start := 0
+	for end := 1; end <= len(entries); end++ {
+		if end < len(entries) && groups[end] == groups[start] {
+			continue
+		}
+		var keyRun []fileEntry
+		if keys != nil {
+			keyRun = keys[start:end]
+		}
+		self.splitByContent(entries[start:end], keyRun, groups[start:end], start)
+		start = end
+	}
+}
+
+// Split a match group by content. Each file is compared with the first file
+// of each content group so far, and starts a new content group if it matches
+// none of them. If the compare key has a digest, a new content group is a
+// hash collision, which is reported as an error. Entries loaded from FSIFT
+// files are read at their saved absolute path in the current file system.
+// Directories, and entries without an absolute path (such as those loaded
+// from FSIFT files saved without it), can't be compared, so they stay in the
+// first content group. A file that can't be read gets its own group, and if
+// it is the first file of a group, later files aren't compared with it.
+// Group IDs are firstId plus the position of each group's first entry.
+func (self *Context) splitByContent(run, keys []fileEntry, groups []int, firstId int) {
+	n := len(run)
+	content := make([]int, n) // content group of each entry
+	var firsts []string       // path of the first file of each content group
+	for i, entry := range run {
+		path, ok := entry.getStringField(ColAbsPath)
+		if strings.HasSuffix(entryPath(entry), "/") {
+			continue
+		}
+		if !ok {
+			self.unverified = true
+			continue
+		}
+		content[i] = -1
+		failed := false
+		compared := "" // the first file compared with, if any
+		for c, first := range firsts {
+			if first == "" {
+				continue // the first file couldn't be read
+			}
+			same, err := self.sameFileContent(first, path)
+			if err != nil {
+				self.onError("Error verifying file content: ", err)
+				_, pathErr := os.Stat(path)
+				if _, firstErr := os.Stat(first); pathErr == nil && firstErr != nil {
+					// the first file failed; compare with the other groups
+					firsts[c] = ""
+					continue
+				}
+				failed = true
+				break
+			}
+			if compared == "" {
+				compared = first
+			}
+			if same {
+				content[i] = c
+				break
+			}
+		}
+		if content[i] < 0 {
+			if compared != "" && !failed && self.keyHasDigest() {
+				self.onError("Hash collision: ", compared, " and ", path, " have the same key but different content")
+			}
+			content[i] = len(firsts)
+			if failed {
+				path = "" // later files aren't compared with it
+			}
+			firsts = append(firsts, path)
+		}
+	}
+	if len(firsts) < 2 {
+		return
+	}
+
+	// reorder the run so that content groups are contiguous, in order of
+	// their first files
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return content[order[a]] < content[order[b]] })
+	sorted := make([]fileEntry, n)
+	var sortedKeys []fileEntry
+	if keys != nil {
+		sortedKeys = make([]fileEntry, n)
+	}
+	for i, k := range order {
+		sorted[i] = run[k]
+		if keys != nil {
+			sortedKeys[i] = keys[k]
+		}
+		if i > 0 && content[k] == content[order[i-1]] {
+			groups[i] = groups[i-1]
+		} else {
+			groups[i] = firstId + i
+		}
+	}
+	copy(run, sorted)
+	copy(keys, sortedKeys)
+}
+
+// Return true if the compare key has a digest column.
+func (self *Context) keyHasDigest() bool {
+	for _, col := range self.KeyCols.cols {
+		if containsCol(digestColumns, col) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+	Copyright (C) 2017  John Thayer
+
+	This program is free software; you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation; either version 2 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along
+	with this program; if not, write to the Free Software Foundation, Inc.,
+	51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+*/
+
+package sifter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Context_verifyMatchGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for name, data := range map[string]string{"a": "same", "b": "diff", "c": "same", "d": "other", "e": "diff"} {
+		ioutil.WriteFile(path(name), []byte(data), 0666)
+	}
+	entry := func(name string, crc string) fileEntry {
+		return fileEntry{ColPath: name, ColAbsPath: path(name), ColSize: int64(4), ColCrc32: crc}
+	}
+	var tests = []struct {
+		keyCols    []Column
+		collisions int
+		want       [][]string
+		ids        []int
+	}{
+		// a lone file isn't read, so z is only an error when compared
+		{[]Column{ColCrc32}, 1, [][]string{{"a", "c", "x"}, {"b", "e"}, {"d"}, {"y/", "z"}}, []int{0, 0, 0, 3, 3, 5, 6, 6}},
+		{[]Column{ColSize}, 0, [][]string{{"a", "c", "x", "y/"}, {"b", "e"}, {"d"}, {"z"}}, []int{0, 0, 0, 0, 4, 4, 6, 7}},
+	}
+	for _, test := range tests {
+		ctx := NewContext()
+		ctx.VerifyContent = true
+		ctx.KeyCols.cols = test.keyCols
+		entries := []fileEntry{
+			entry("a", "1"), entry("b", "1"), entry("c", "1"),
+			{ColPath: "x", ColSize: int64(4), ColCrc32: "1"},
+			entry("e", "1"), entry("d", "2"),
+			{ColPath: "y/", ColSize: int64(4), ColCrc32: "3"},
+			{ColPath: "z", ColAbsPath: path("none"), ColSize: int64(4), ColCrc32: "3"},
+		}
+		groups := ctx.findMatchGroups(entries, nil)
+		var got [][]string
+		for i, e := range entries {
+			if i == 0 || groups[i] != groups[i-1] {
+				got = append(got, nil)
+			}
+			got[len(got)-1] = append(got[len(got)-1], entryPath(e))
+		}
+		checkVal(t, test.want, got)
+		checkVal(t, test.ids, groups)
+		checkVal(t, true, ctx.unverified)
+		collisions := 0
+		for _, msg := range ctx.errorMessages {
+			if strings.Contains(msg, "Hash collision: "+path("a")+" and "+path("b")) {
+				collisions++
+			}
+		}
+		checkVal(t, test.collisions, collisions)
+		checkVal(t, 1, ctx.errorCount)
+	}
+}
+
+func Test_Context_splitByContent_unreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for name, data := range map[string]string{"a": "same", "b": "diff", "c": "same"} {
+		ioutil.WriteFile(path(name), []byte(data), 0666)
+	}
+	entry := func(name string) fileEntry {
+		return fileEntry{ColPath: name, ColAbsPath: path(name), ColSize: int64(4)}
+	}
+
+	// an unreadable first file doesn't split up the files after it, and an
+	// unreadable later file is only reported once
+	ctx := NewContext()
+	ctx.KeyCols.cols = []Column{ColSize}
+	run := []fileEntry{entry("gone"), entry("a"), entry("b"), entry("c")}
+	groups := make([]int, len(run))
+	ctx.splitByContent(run, nil, groups, 0)
+	checkVal(t, []fileEntry{entry("gone"), entry("a"), entry("c"), entry("b")}, run)
+	checkVal(t, []int{0, 1, 1, 3}, groups)
+	checkVal(t, 1, ctx.errorCount)
+
+	ctx = NewContext()
+	ctx.KeyCols.cols = []Column{ColSize}
+	run = []fileEntry{entry("a"), entry("gone"), entry("b"), entry("c")}
+	ctx.splitByContent(run, nil, groups, 0)
+	checkVal(t, []fileEntry{entry("a"), entry("c"), entry("gone"), entry("b")}, run)
+	checkVal(t, []int{0, 0, 2, 3}, groups)
+	checkVal(t, 1, ctx.errorCount)
+}
+
+func Test_Context_sameFileContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsift-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	ioutil.WriteFile(a, []byte("abc"), 0666)
+	ioutil.WriteFile(b, []byte("abc"), 0666)
+	ioutil.WriteFile(c, []byte("abcd"), 0666)
+	ctx := NewContext()
+	same, err := ctx.sameFileContent(b, a)
+	checkValErr1(t, true, same, "", err)
+	same, err = ctx.sameFileContent(a, c)
+	checkValErr1(t, false, same, "", err)
+	checkVal(t, contentCache{{a, b}: true, {a, c}: false}, ctx.verified)
+	// remembered results don't read the files again
+	os.Remove(b)
+	same, err = ctx.sameFileContent(a, b)
+	checkValErr1(t, true, same, "", err)
+}